@@ -1,3 +1,12 @@
 module github.com/semka95/gophercises/ex1
 
-go 1.14
+go 1.17
+
+require gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
+
+require (
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/text v0.13.0
+)
+
+require golang.org/x/sys v0.5.0 // indirect
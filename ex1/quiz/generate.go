@@ -0,0 +1,382 @@
+package quiz
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// defaultGenerated is the number of generated problems if it
+	// isn't set
+	defaultGenerated = 10
+	// maxGenerateTries limits retries of template which gives
+	// invalid expression, e.g. division by zero
+	maxGenerateTries = 100
+	// answerPrecision is the number of decimal places of generated
+	// answers which aren't integer
+	answerPrecision = 2
+)
+
+// placeholder matches "{a:1..20}" which defines variable a with
+// random value from the range and "{a}" which refers to it
+var placeholder = regexp.MustCompile(`\{(\w+)(?::\s*(-?\d+)\s*\.\.\s*(-?\d+))?\s*\}`)
+
+// Template generates arithmetic problems, e.g. "{a:1..20} * {b:1..12}".
+// The answer is the value of the question expression or of the
+// expression after " = ", e.g. "Square of {a:2..9} = a * a".
+type Template struct {
+	question string
+	answer   string
+	ranges   map[string]valueRange
+}
+
+// valueRange is the range of random values of a variable, values
+// are lo + [0, n)
+type valueRange struct {
+	lo int
+	n  int
+}
+
+// parseRange parses bounds of the variable range, the number of
+// values must fit int
+func parseRange(name, lo, hi string) (valueRange, error) {
+	l, err := strconv.Atoi(lo)
+	if err != nil {
+		return valueRange{}, fmt.Errorf("bad range of variable %q: %w", name, err)
+	}
+	h, err := strconv.Atoi(hi)
+	if err != nil {
+		return valueRange{}, fmt.Errorf("bad range of variable %q: %w", name, err)
+	}
+	if l > h {
+		return valueRange{}, fmt.Errorf("bad range of variable %q: %d..%d", name, l, h)
+	}
+	// the difference of bounds is exact in uint as h >= l
+	if uint(h)-uint(l) >= math.MaxInt {
+		return valueRange{}, fmt.Errorf("range of variable %q is too large: %d..%d", name, l, h)
+	}
+
+	return valueRange{lo: l, n: h - l + 1}, nil
+}
+
+// ParseTemplate parses template and checks its placeholders and
+// answer expression
+func ParseTemplate(s string) (Template, error) {
+	t := Template{question: strings.TrimSpace(s)}
+	if i := strings.LastIndex(t.question, " = "); i >= 0 {
+		t.question, t.answer = strings.TrimSpace(t.question[:i]), strings.TrimSpace(t.question[i+3:])
+	}
+	if t.question == "" {
+		return Template{}, errors.New("template is empty")
+	}
+
+	t.ranges = make(map[string]valueRange)
+	for _, m := range placeholder.FindAllStringSubmatch(t.question, -1) {
+		name := m[1]
+		_, defined := t.ranges[name]
+		if m[2] == "" {
+			if !defined {
+				return Template{}, fmt.Errorf("variable %q is used before definition", name)
+			}
+			continue
+		}
+		if defined {
+			return Template{}, fmt.Errorf("variable %q is defined twice", name)
+		}
+
+		vr, err := parseRange(name, m[2], m[3])
+		if err != nil {
+			return Template{}, err
+		}
+		t.ranges[name] = vr
+	}
+	if t.answer == "" && len(t.ranges) == 0 {
+		return Template{}, errors.New("template has no variables")
+	}
+
+	// check syntax of the answer with the lowest values
+	vars := make(map[string]float64)
+	for name, vr := range t.ranges {
+		vars[name] = float64(vr.lo)
+	}
+	_, err := eval(t.expression(vars), vars)
+	if err != nil && !errors.Is(err, errDivisionByZero) {
+		return Template{}, err
+	}
+
+	return t, nil
+}
+
+// ParseTemplates reads templates, one per line, blank lines and
+// lines starting with "#" are skipped
+func ParseTemplates(r io.Reader) ([]Template, error) {
+	var templates []Template
+
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+
+		t, err := ParseTemplate(s)
+		if err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		templates = append(templates, t)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(templates) == 0 {
+		return nil, errors.New("no templates")
+	}
+
+	return templates, nil
+}
+
+// LoadTemplates reads templates from file
+func LoadTemplates(fileName string) ([]Template, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	templates, err := ParseTemplates(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", fileName, err)
+	}
+
+	return templates, nil
+}
+
+// Generate returns n problems from randomly picked templates, the
+// same seed always gives the same problems
+func Generate(templates []Template, n int, seed int64) ([]Problem, error) {
+	if len(templates) == 0 {
+		return nil, errors.New("no templates")
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	problems := make([]Problem, 0, n)
+	for len(problems) < n {
+		p, err := templates[r.Intn(len(templates))].generate(r)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, p)
+	}
+
+	return problems, nil
+}
+
+// generate picks random values of variables and computes the answer,
+// values giving invalid expression are picked again
+func (t Template) generate(r *rand.Rand) (Problem, error) {
+	var err error
+	for i := 0; i < maxGenerateTries; i++ {
+		vars := make(map[string]float64)
+		question := placeholder.ReplaceAllStringFunc(t.question, func(s string) string {
+			m := placeholder.FindStringSubmatch(s)
+			if m[2] != "" {
+				vr := t.ranges[m[1]]
+				vars[m[1]] = float64(vr.lo + r.Intn(vr.n))
+			}
+			return strconv.FormatFloat(vars[m[1]], 'f', -1, 64)
+		})
+
+		var v float64
+		v, err = eval(t.expression(vars), vars)
+		if err != nil {
+			continue
+		}
+
+		p := Problem{Kind: KindNumeric, Question: question}
+		rounded := math.Round(v*math.Pow10(answerPrecision)) / math.Pow10(answerPrecision)
+		p.Answer = strconv.FormatFloat(rounded, 'f', -1, 64)
+		if rounded != v {
+			p.Tolerance = math.Pow10(-answerPrecision)
+		}
+		return p, nil
+	}
+
+	return Problem{}, fmt.Errorf("template %q: %w", t.question, err)
+}
+
+// expression returns the answer expression with placeholders
+// replaced by values
+func (t Template) expression(vars map[string]float64) string {
+	expr := t.answer
+	if expr == "" {
+		expr = t.question
+	}
+	return placeholder.ReplaceAllStringFunc(expr, func(s string) string {
+		name := placeholder.FindStringSubmatch(s)[1]
+		return "(" + strconv.FormatFloat(vars[name], 'f', -1, 64) + ")"
+	})
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+// eval computes arithmetic expression with numbers, variables,
+// parentheses and operators + - * / % (× and ÷ are accepted too,
+// x isn't as it can't be told from variable names)
+func eval(s string, vars map[string]float64) (float64, error) {
+	p := exprParser{s: []rune(s), vars: vars}
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return 0, fmt.Errorf("unexpected %q in expression %q", p.s[p.pos], s)
+	}
+
+	return v, nil
+}
+
+// exprParser is recursive descent parser of arithmetic expressions
+type exprParser struct {
+	s    []rune
+	pos  int
+	vars map[string]float64
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space rune or 0 at the end
+func (p *exprParser) peek() rune {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// expr parses sum of terms
+func (p *exprParser) expr() (float64, error) {
+	v, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return v, nil
+		}
+		p.pos++
+
+		t, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			v += t
+		} else {
+			v -= t
+		}
+	}
+}
+
+// term parses product of factors
+func (p *exprParser) term() (float64, error) {
+	v, err := p.factor()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		switch op {
+		case '*', '×', '/', '÷', '%':
+		default:
+			return v, nil
+		}
+		p.pos++
+
+		f, err := p.factor()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*', '×':
+			v *= f
+		case '/', '÷':
+			if f == 0 {
+				return 0, errDivisionByZero
+			}
+			v /= f
+		case '%':
+			if f == 0 {
+				return 0, errDivisionByZero
+			}
+			v = math.Mod(v, f)
+		}
+	}
+}
+
+// factor parses number, variable, parenthesized expression or
+// factor with unary sign
+func (p *exprParser) factor() (float64, error) {
+	c := p.peek()
+	switch {
+	case c == '-' || c == '+':
+		p.pos++
+		v, err := p.factor()
+		if c == '-' {
+			v = -v
+		}
+		return v, err
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return v, nil
+	case unicode.IsDigit(c) || c == '.':
+		start := p.pos
+		for p.pos < len(p.s) && (unicode.IsDigit(p.s[p.pos]) || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		return strconv.ParseFloat(string(p.s[start:p.pos]), 64)
+	case unicode.IsLetter(c) || c == '_':
+		start := p.pos
+		for p.pos < len(p.s) && (unicode.IsLetter(p.s[p.pos]) || unicode.IsDigit(p.s[p.pos]) || p.s[p.pos] == '_') {
+			p.pos++
+		}
+		name := string(p.s[start:p.pos])
+		v, ok := p.vars[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown variable %q", name)
+		}
+		return v, nil
+	case c == 0:
+		return 0, errors.New("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q", c)
+	}
+}
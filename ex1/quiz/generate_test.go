@@ -0,0 +1,125 @@
+package quiz
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"2 + 3 * 4", 14},
+		{"(2 + 3) * 4", 20},
+		{"10 - 4 - 3", 3},
+		{"7 / 2", 3.5},
+		{"7 % 3", 1},
+		{"-a * 2", -10},
+		{"6 × 7 ÷ 2", 21},
+	}
+	for _, test := range tests {
+		got, err := eval(test.expr, map[string]float64{"a": 5})
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.expr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got %v, want %v", test.expr, got, test.want)
+		}
+	}
+
+	for _, expr := range []string{"", "1 +", "(1 + 2", "b * 2", "1 / 0", "2 $ 3", "3x4", "a x a"} {
+		if _, err := eval(expr, nil); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	for _, s := range []string{
+		"{a:1..20} * {b:1..12}",
+		"Square of {a:2..9} = a * a",
+		"{a:1..9} + {a}",
+		"{a:0..5} / {b:0..5}",
+		"{a:-9223372036854775806..0} + 1",
+		"{a:5..5} * {b:-3..-1}",
+	} {
+		if _, err := ParseTemplate(s); err != nil {
+			t.Errorf("%q: unexpected error: %v", s, err)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"no variables",
+		"{a} + {b:1..2}",
+		"{a:9..1} + 1",
+		"{a:1..9} + = b",
+		"{a:1..5} + {a:10..12}",
+		"{a:1..99999999999999999999} + 1",
+		"{a:-9223372036854775808..9223372036854775807} + 1",
+		"{x:1..3}x2",
+	} {
+		if _, err := ParseTemplate(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	templates, err := ParseTemplates(strings.NewReader(`
+# multiplication table
+{a:1..20} * {b:1..12}
+Square of {a:2..9} = a * a
+{a:1..9} / {b:0..3}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	problems, err := Generate(templates, 50, 42)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(problems) != 50 {
+		t.Fatalf("got %d problems, want 50", len(problems))
+	}
+
+	for _, p := range problems {
+		if err := p.validate(); err != nil {
+			t.Errorf("%q: %v", p.Question, err)
+		}
+
+		expr := p.Question
+		if strings.HasPrefix(expr, "Square of ") {
+			n := strings.TrimPrefix(expr, "Square of ")
+			expr = n + " * " + n
+		}
+		want, err := eval(expr, nil)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", p.Question, err)
+		}
+		if !p.Check(strconv.FormatFloat(want, 'f', -1, 64), DefaultPolicy) {
+			t.Errorf("%q: answer %v isn't accepted, want %v", p.Question, p.Answer, want)
+		}
+	}
+
+	again, err := Generate(templates, 50, 42)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(problems, again) {
+		t.Error("got different problems with the same seed")
+	}
+}
+
+func TestParseTemplatesError(t *testing.T) {
+	_, err := ParseTemplates(strings.NewReader("{a:1..2} + 1\n{b}\n"))
+	pe, ok := err.(*ParseError)
+	if !ok || pe.Line != 2 {
+		t.Errorf("got error %v, want parse error on line 2", err)
+	}
+}
@@ -0,0 +1,240 @@
+package quiz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	defaultHistory = "./quiz.db"
+	attemptsBucket = "attempts"
+	cardsBucket    = "cards"
+)
+
+// Attempt is a single quiz session stored in history
+type Attempt struct {
+	ID         int       `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	File       string    `json:"file"`
+	FileHash   string    `json:"file_hash"`
+	Seed       int64     `json:"seed"`
+	Reason     Reason    `json:"reason"`
+	Score      Score     `json:"score"`
+	Answers    []Answer  `json:"answers"`
+}
+
+// Answer is the stored result of a single problem
+type Answer struct {
+	Question string        `json:"question"`
+	Answer   string        `json:"answer"`
+	Correct  bool          `json:"correct"`
+	TimedOut bool          `json:"timed_out"`
+	Hinted   bool          `json:"hinted,omitempty"`
+	Latency  time.Duration `json:"latency"`
+}
+
+// newAttempt converts finished session to Attempt
+func newAttempt(s *Session, file, hash string) Attempt {
+	reason, _ := s.Finished()
+	a := Attempt{
+		StartedAt:  s.StartedAt(),
+		FinishedAt: time.Now(),
+		File:       file,
+		FileHash:   hash,
+		Seed:       s.Seed(),
+		Reason:     reason,
+		Score:      s.Score(),
+	}
+
+	for _, res := range s.Results() {
+		a.Answers = append(a.Answers, resultAnswer(res))
+	}
+
+	return a
+}
+
+// resultAnswer converts Result to Answer
+func resultAnswer(r Result) Answer {
+	return Answer{
+		Question: r.Problem.Question,
+		Answer:   r.Answer,
+		Correct:  r.Correct,
+		TimedOut: r.TimedOut,
+		Hinted:   r.Hinted,
+		Latency:  r.Duration,
+	}
+}
+
+// History represents storage for quiz attempts and spaced
+// repetition cards
+type History interface {
+	GetAll() ([]Attempt, error)
+	Store(a Attempt) (int, error)
+	GetCards(fileHash string) (map[string]Card, error)
+	StoreCard(fileHash string, c Card) error
+	Close() error
+}
+
+// BoltHistory represents BoltDB History
+type BoltHistory struct {
+	db         *bolt.DB
+	bucketName []byte
+}
+
+// NewBoltHistory creates new instance of BoltHistory
+func NewBoltHistory(path string) (BoltHistory, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return BoltHistory{}, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{attemptsBucket, cardsBucket} {
+			_, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return BoltHistory{}, err
+	}
+
+	h := BoltHistory{
+		db:         db,
+		bucketName: []byte(attemptsBucket),
+	}
+
+	return h, nil
+}
+
+// GetAll returns all attempts in the order they were stored
+func (h BoltHistory) GetAll() ([]Attempt, error) {
+	var attempts []Attempt
+	err := h.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(h.bucketName)
+		return b.ForEach(func(k, v []byte) error {
+			var a Attempt
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+
+			attempts = append(attempts, a)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// Store puts single attempt to database
+func (h BoltHistory) Store(a Attempt) (int, error) {
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(h.bucketName)
+		id64, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		a.ID = int(id64)
+
+		buf, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(itob(a.ID), buf)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return a.ID, nil
+}
+
+// GetCards returns spaced repetition cards of the problems file
+// by question
+func (h BoltHistory) GetCards(fileHash string) (map[string]Card, error) {
+	cards := make(map[string]Card)
+	err := h.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cardsBucket)).Bucket([]byte(fileHash))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var c Card
+			if err := json.Unmarshal(v, &c); err != nil {
+				return err
+			}
+
+			cards[c.Question] = c
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+// StoreCard puts spaced repetition card of the problems file
+// to database
+func (h BoltHistory) StoreCard(fileHash string, c Card) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket([]byte(cardsBucket)).CreateBucketIfNotExists([]byte(fileHash))
+		if err != nil {
+			return err
+		}
+
+		buf, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(c.Question), buf)
+	})
+}
+
+// Close closes database
+func (h BoltHistory) Close() error {
+	return h.db.Close()
+}
+
+// itob returns an 8-byte big endian representation of v
+func itob(v int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+// hashFile returns hex encoded SHA-256 of file content, it
+// identifies problem set in history
+func hashFile(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,62 @@
+package quiz
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoltHistory(t *testing.T) {
+	h, err := NewBoltHistory(filepath.Join(t.TempDir(), "quiz.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer h.Close()
+
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+		},
+	}
+	s := quiz.Start(context.Background())
+	if _, err := s.Submit("10"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Submit("3"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		id, err := h.Store(newAttempt(s, "problems.csv", "hash"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != i {
+			t.Errorf("got id %d, want %d", id, i)
+		}
+	}
+
+	attempts, err := h.GetAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(attempts))
+	}
+
+	a := attempts[0]
+	if a.ID != 1 || a.FileHash != "hash" || a.Reason != ReasonCompleted || a.Score != s.Score() {
+		t.Errorf("got unexpected attempt %+v", a)
+	}
+	if len(a.Answers) != 2 || !a.Answers[0].Correct || a.Answers[1].Correct || a.Answers[1].Answer != "3" {
+		t.Errorf("got unexpected answers %+v", a.Answers)
+	}
+	if !a.StartedAt.Equal(s.StartedAt()) {
+		t.Errorf("got start time %v, want %v", a.StartedAt, s.StartedAt())
+	}
+	if a.FinishedAt.Before(a.StartedAt) || a.FinishedAt.After(time.Now()) {
+		t.Errorf("got bad finish time %v", a.FinishedAt)
+	}
+}
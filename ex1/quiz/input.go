@@ -0,0 +1,39 @@
+package quiz
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// readLines starts single goroutine which reads r line by line
+// and sends every line to the returned channel until ctx is done.
+// Read error (io.EOF at the end of input) is sent to the error
+// channel after which the goroutine exits.
+//
+// Goroutine blocked on reading r can't be interrupted, but it
+// doesn't prevent the caller from returning once ctx is done.
+func readLines(ctx context.Context, r io.Reader) (<-chan string, <-chan error) {
+	lines := make(chan string)
+	errCh := make(chan error, 1)
+
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				errCh <- err
+				return
+			}
+
+			select {
+			case lines <- strings.TrimRight(line, "\r\n"):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return lines, errCh
+}
@@ -0,0 +1,112 @@
+package quiz
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Policy defines how user's answer is compared with the
+// expected one
+type Policy struct {
+	// FoldCase makes comparison case-insensitive
+	FoldCase bool
+	// TrimSpace removes leading and trailing whitespace and
+	// collapses inner whitespace to a single space
+	TrimSpace bool
+	// Normalize applies Unicode NFKC normalization, so composed
+	// and decomposed forms of the same text are equal
+	Normalize bool
+	// MaxDistance is the maximum Levenshtein distance between
+	// non-numeric answers which is still considered correct
+	MaxDistance int
+	// Numeric treats answers as equal if both of them are
+	// numbers with the same value, e.g. "10" and "10.0"
+	Numeric bool
+}
+
+// StrictPolicy compares answers byte-for-byte
+var StrictPolicy = Policy{}
+
+// DefaultPolicy ignores surrounding whitespace and letter case
+var DefaultPolicy = Policy{FoldCase: true, TrimSpace: true}
+
+// Equal reports whether answer matches expected value
+func (p Policy) Equal(answer, expected string) bool {
+	answer, expected = p.normalize(answer), p.normalize(expected)
+	if answer == expected {
+		return true
+	}
+
+	a, aErr := strconv.ParseFloat(answer, 64)
+	e, eErr := strconv.ParseFloat(expected, 64)
+	if eErr == nil {
+		// typos are never tolerated in numbers: 10 and 11
+		// are just one edit apart
+		return p.Numeric && aErr == nil && a == e
+	}
+
+	return p.MaxDistance > 0 && levenshtein(answer, expected) <= p.MaxDistance
+}
+
+// normalize converts s to the canonical form used for
+// comparison
+func (p Policy) normalize(s string) string {
+	if p.Normalize {
+		s = norm.NFKC.String(s)
+	}
+	if p.TrimSpace {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	if p.FoldCase {
+		s = strings.ToLower(s)
+	}
+
+	return s
+}
+
+// levenshtein returns the number of single rune edits needed
+// to turn a into b
+func levenshtein(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if utf8.RuneCountInString(a) < utf8.RuneCountInString(b) {
+		a, b = b, a
+	}
+
+	rb := []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	i := 0
+	for _, ra := range a {
+		i++
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,52 @@
+package quiz
+
+import "testing"
+
+func TestPolicyEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		answer   string
+		expected string
+		want     bool
+	}{
+		{"strict equal", StrictPolicy, "Paris", "Paris", true},
+		{"strict case", StrictPolicy, "paris", "Paris", false},
+		{"strict space", StrictPolicy, "Paris ", "Paris", false},
+		{"default case and space", DefaultPolicy, " paris ", "Paris", true},
+		{"default inner space", DefaultPolicy, "New   York", "new york", true},
+		{"numeric disabled", DefaultPolicy, "10.0", "10", false},
+		{"numeric enabled", Policy{Numeric: true}, "10.0", "10", true},
+		{"numeric different", Policy{Numeric: true}, "10.5", "10", false},
+		{"decomposed without normalization", StrictPolicy, "cafe\u0301", "caf\u00e9", false},
+		{"decomposed with normalization", Policy{Normalize: true}, "cafe\u0301", "caf\u00e9", true},
+		{"typo within distance", Policy{MaxDistance: 1}, "Pariss", "Paris", true},
+		{"typo out of distance", Policy{MaxDistance: 1}, "Parsi", "Paris", false},
+		{"distance ignored for numbers", Policy{MaxDistance: 1}, "11", "10", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.Equal(test.answer, test.expected); got != test.want {
+				t.Errorf("Equal(%q, %q) = %v, want %v", test.answer, test.expected, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"héllo", "hello", 1},
+	}
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package quiz
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// defaultEase is the initial ease factor of SM-2 algorithm
+	defaultEase = 2.5
+	// minEase is the lowest ease factor of SM-2 algorithm
+	minEase = 1.3
+	day     = 24 * time.Hour
+
+	// answers faster than fastAnswer get the best quality grade,
+	// answers slower than slowAnswer get the worst passing one
+	fastAnswer = 5 * time.Second
+	slowAnswer = 15 * time.Second
+)
+
+// Card is spaced repetition state of a single question
+type Card struct {
+	Question    string `json:"question"`
+	Repetitions int    `json:"repetitions"`
+	// Interval is the number of days until the next review
+	Interval int       `json:"interval"`
+	Ease     float64   `json:"ease"`
+	Due      time.Time `json:"due"`
+	Reviewed time.Time `json:"reviewed"`
+}
+
+// newCard creates card of the question which was never reviewed,
+// it is due immediately
+func newCard(question string) Card {
+	return Card{Question: question, Ease: defaultEase}
+}
+
+// review updates card with answer quality from 0 to 5 according
+// to SM-2 algorithm
+func (c *Card) review(quality int, at time.Time) {
+	if quality >= 3 {
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Repetitions++
+	} else {
+		c.Repetitions = 0
+		c.Interval = 1
+	}
+
+	miss := float64(5 - quality)
+	c.Ease += 0.1 - miss*(0.08+miss*0.02)
+	if c.Ease < minEase {
+		c.Ease = minEase
+	}
+
+	c.Reviewed = at
+	c.Due = at.Add(time.Duration(c.Interval) * day)
+}
+
+// apply reviews card with the answer given at, passing answers
+// given before the card is due are skipped as practicing early
+// doesn't grow the interval, failures always reset it
+func (c *Card) apply(a Answer, at time.Time) {
+	q := quality(a)
+	if q >= 3 && at.Before(c.Due) {
+		return
+	}
+	c.review(q, at)
+}
+
+// quality grades answer from 0 to 5, correct answers are graded
+// by response time unless hint was used
+func quality(a Answer) int {
+	switch {
+	case a.TimedOut:
+		return 0
+	case !a.Correct:
+		return 1
+	case a.Hinted:
+		return 3
+	case a.Latency < fastAnswer:
+		return 5
+	case a.Latency < slowAnswer:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// replayCard builds card of the question from answers stored
+// in history
+func replayCard(question string, attempts []Attempt) Card {
+	c := newCard(question)
+	for _, a := range attempts {
+		for _, ans := range a.Answers {
+			if ans.Question == question {
+				c.apply(ans, a.FinishedAt)
+			}
+		}
+	}
+	return c
+}
+
+// updateCards applies answers of the attempt recorded outside of
+// practice mode to stored cards, questions without cards are
+// replayed from history when they are practiced first
+func updateCards(h History, a Attempt) error {
+	cards, err := h.GetCards(a.FileHash)
+	if err != nil {
+		return err
+	}
+
+	for _, ans := range a.Answers {
+		c, ok := cards[ans.Question]
+		if !ok {
+			continue
+		}
+		c.apply(ans, a.FinishedAt)
+		cards[ans.Question] = c
+		if err := h.StoreCard(a.FileHash, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// schedule returns cards of all problems, cards missing in
+// history are replayed from past attempts
+func schedule(problems []Problem, cards map[string]Card, attempts []Attempt) map[string]Card {
+	scheduled := make(map[string]Card, len(problems))
+	for _, p := range problems {
+		c, ok := cards[p.Question]
+		if !ok {
+			c = replayCard(p.Question, attempts)
+		}
+		scheduled[p.Question] = c
+	}
+	return scheduled
+}
+
+// dueProblems returns problems due by now, the most overdue
+// first
+func dueProblems(problems []Problem, cards map[string]Card, now time.Time) []Problem {
+	var due []Problem
+	for _, p := range problems {
+		if !cards[p.Question].Due.After(now) {
+			due = append(due, p)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return cards[due[i].Question].Due.Before(cards[due[j].Question].Due)
+	})
+
+	return due
+}
+
+// nextDue returns the earliest due time of all cards
+func nextDue(cards map[string]Card) time.Time {
+	var next time.Time
+	first := true
+	for _, c := range cards {
+		if first || c.Due.Before(next) {
+			next = c.Due
+			first = false
+		}
+	}
+	return next
+}
+
+// runPractice asks only problems due for review and reschedules
+// them after every answer
+func (app *appEnv) runPractice(ctx context.Context, quiz Quiz) error {
+	h, err := NewBoltHistory(app.history)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer h.Close()
+
+	stored, err := h.GetCards(app.fileHash)
+	if err != nil {
+		return err
+	}
+	attempts, err := h.GetAll()
+	if err != nil {
+		return err
+	}
+	cards := schedule(quiz.Problems, stored, filterAttempts(attempts, app.fileHash))
+
+	due := dueProblems(quiz.Problems, cards, time.Now())
+	if len(due) == 0 {
+		fmt.Fprintf(app.term.Out, "Nothing to practice, the next problem is due at %v.\n", nextDue(cards).Format(time.RFC1123))
+		return nil
+	}
+	if quiz.Count > 0 && quiz.Count < len(due) {
+		due = due[:quiz.Count]
+	}
+	quiz.Problems = due
+	quiz.Count = 0
+
+	quiz.OnResult = func(r Result) {
+		c := cards[r.Problem.Question]
+		c.review(quality(resultAnswer(r)), time.Now())
+		cards[r.Problem.Question] = c
+
+		if err := h.StoreCard(app.fileHash, c); err != nil {
+			fmt.Fprintf(os.Stderr, "save card: %v\n", err)
+		}
+	}
+
+	s := quiz.Start(ctx)
+	if err := app.term.Run(ctx, s); err != nil {
+		return err
+	}
+
+	if _, err := h.Store(newAttempt(s, app.fileName, app.fileHash)); err != nil {
+		return fmt.Errorf("save history: %w", err)
+	}
+
+	return app.finish(s)
+}
@@ -0,0 +1,178 @@
+package quiz
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCardReview(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		qualities []int
+		interval  int
+		reps      int
+		ease      float64
+	}{
+		{"first correct", []int{5}, 1, 1, 2.6},
+		{"second correct", []int{5, 5}, 6, 2, 2.7},
+		{"third correct", []int{5, 5, 4}, 16, 3, 2.7},
+		{"wrong resets", []int{5, 5, 1}, 1, 0, 2.16},
+		{"ease lower bound", []int{0, 0, 0, 0}, 1, 0, minEase},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCard("q")
+			for _, q := range test.qualities {
+				c.review(q, now)
+			}
+
+			if c.Interval != test.interval || c.Repetitions != test.reps {
+				t.Errorf("got interval %d and repetitions %d, want %d and %d", c.Interval, c.Repetitions, test.interval, test.reps)
+			}
+			if diff := c.Ease - test.ease; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("got ease %v, want %v", c.Ease, test.ease)
+			}
+			if want := now.Add(time.Duration(test.interval) * day); !c.Due.Equal(want) {
+				t.Errorf("got due %v, want %v", c.Due, want)
+			}
+		})
+	}
+}
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		answer Answer
+		want   int
+	}{
+		{Answer{TimedOut: true}, 0},
+		{Answer{Correct: false, Latency: time.Second}, 1},
+		{Answer{Correct: true, Latency: time.Second}, 5},
+		{Answer{Correct: true, Latency: 10 * time.Second}, 4},
+		{Answer{Correct: true, Latency: time.Minute}, 3},
+	}
+	for _, test := range tests {
+		if got := quality(test.answer); got != test.want {
+			t.Errorf("quality(%+v) = %d, want %d", test.answer, got, test.want)
+		}
+	}
+}
+
+func TestReplayCard(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	answer := []Answer{{Question: "q", Correct: true}}
+
+	// the same question answered three times on the first day is
+	// reviewed once, the answer after it is due counts again
+	attempts := []Attempt{
+		{FinishedAt: now, Answers: answer},
+		{FinishedAt: now.Add(time.Hour), Answers: answer},
+		{FinishedAt: now.Add(2 * time.Hour), Answers: answer},
+		{FinishedAt: now.Add(day), Answers: answer},
+	}
+
+	c := replayCard("q", attempts)
+	if c.Repetitions != 2 || c.Interval != 6 {
+		t.Errorf("got repetitions %d and interval %d, want 2 and 6", c.Repetitions, c.Interval)
+	}
+	if want := now.Add(7 * day); !c.Due.Equal(want) {
+		t.Errorf("got due %v, want %v", c.Due, want)
+	}
+
+	// wrong answer resets the card even before it is due
+	attempts = append(attempts, Attempt{FinishedAt: now.Add(2 * day), Answers: []Answer{{Question: "q"}}})
+	c = replayCard("q", attempts)
+	if c.Repetitions != 0 || c.Interval != 1 {
+		t.Errorf("got repetitions %d and interval %d, want reset card", c.Repetitions, c.Interval)
+	}
+}
+
+func TestUpdateCards(t *testing.T) {
+	h, err := NewBoltHistory(filepath.Join(t.TempDir(), "quiz.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer h.Close()
+
+	now := time.Now()
+	learned := Card{Question: "learned", Repetitions: 3, Interval: 16, Ease: defaultEase, Due: now.Add(10 * day)}
+	if err := h.StoreCard("hash", learned); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := Attempt{
+		FinishedAt: now,
+		FileHash:   "hash",
+		Answers:    []Answer{{Question: "learned"}, {Question: "new", Correct: true}},
+	}
+	if err := updateCards(h, a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cards, err := h.GetCards("hash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := cards["learned"]; c.Repetitions != 0 || c.Interval != 1 {
+		t.Errorf("got card %+v, want it reset by wrong answer", c)
+	}
+	if _, ok := cards["new"]; ok {
+		t.Error("got card of new question, want it replayed from history")
+	}
+}
+
+func TestDueProblems(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	problems := []Problem{
+		{Question: "new", Answer: "1"},
+		{Question: "learned", Answer: "2"},
+		{Question: "overdue", Answer: "3"},
+	}
+	attempts := []Attempt{
+		{
+			FinishedAt: now.Add(-2 * day),
+			Answers:    []Answer{{Question: "overdue", Correct: true}},
+		},
+	}
+	stored := map[string]Card{
+		"learned": {Question: "learned", Due: now.Add(day)},
+	}
+
+	cards := schedule(problems, stored, attempts)
+	due := dueProblems(problems, cards, now)
+
+	if len(due) != 2 || due[0].Question != "new" || due[1].Question != "overdue" {
+		t.Errorf("got due problems %+v, want new and overdue", due)
+	}
+	if next := nextDue(cards); !next.IsZero() {
+		t.Errorf("got next due %v, want zero time of the new card", next)
+	}
+}
+
+func TestBoltHistoryCards(t *testing.T) {
+	h, err := NewBoltHistory(filepath.Join(t.TempDir(), "quiz.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer h.Close()
+
+	c := newCard("5+5")
+	c.review(5, time.Now())
+	if err := h.StoreCard("hash", c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cards, err := h.GetCards("hash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cards["5+5"]; got.Interval != 1 || !got.Due.Equal(c.Due) {
+		t.Errorf("got card %+v, want %+v", got, c)
+	}
+
+	if cards, _ := h.GetCards("other"); len(cards) != 0 {
+		t.Errorf("got cards %+v of other file", cards)
+	}
+}
@@ -0,0 +1,205 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kind is a type of question which defines how the answer is
+// graded and how the question is rendered
+type Kind string
+
+const (
+	// KindText is a free text question with single answer
+	KindText Kind = "text"
+	// KindChoice is a multiple choice question with lettered options,
+	// answer is the letter or the text of the correct option
+	KindChoice Kind = "choice"
+	// KindNumeric is a numeric question, answer is accepted if it
+	// differs from the expected one by no more than tolerance
+	KindNumeric Kind = "numeric"
+	// KindAnyOf is a free text question accepting any of the listed
+	// answers
+	KindAnyOf Kind = "any"
+)
+
+// maxOptions is the number of letters available to label
+// multiple choice options
+const maxOptions = 26
+
+// Problem represents single quiz question with its answer
+// and optional metadata
+type Problem struct {
+	Kind        Kind     `yaml:"type,omitempty" json:"type,omitempty"`
+	Question    string   `yaml:"question" json:"question"`
+	Answer      string   `yaml:"answer" json:"answer"`
+	Answers     []string `yaml:"answers,omitempty" json:"answers,omitempty"`
+	Options     []string `yaml:"options,omitempty" json:"options,omitempty"`
+	Tolerance   float64  `yaml:"tolerance,omitempty" json:"tolerance,omitempty"`
+	TimeLimit   int64    `yaml:"limit,omitempty" json:"limit,omitempty"`
+	Category    string   `yaml:"category,omitempty" json:"category,omitempty"`
+	Difficulty  string   `yaml:"difficulty,omitempty" json:"difficulty,omitempty"`
+	Explanation string   `yaml:"explanation,omitempty" json:"explanation,omitempty"`
+	Hint        string   `yaml:"hint,omitempty" json:"hint,omitempty"`
+}
+
+// kind returns problem kind, free text is used by default
+func (p Problem) kind() Kind {
+	if p.Kind == "" {
+		return KindText
+	}
+	return p.Kind
+}
+
+// validate checks that problem has all required fields
+// for its kind
+func (p Problem) validate() error {
+	if strings.TrimSpace(p.Question) == "" {
+		return errors.New("question is empty")
+	}
+
+	if p.TimeLimit < 0 {
+		return errors.New("time limit is negative")
+	}
+
+	switch p.kind() {
+	case KindText:
+		if strings.TrimSpace(p.Answer) == "" {
+			return errors.New("answer is empty")
+		}
+	case KindChoice:
+		if len(p.Options) < 2 || len(p.Options) > maxOptions {
+			return fmt.Errorf("wrong number of options: %d instead of 2-%d", len(p.Options), maxOptions)
+		}
+		if p.correctOption() < 0 {
+			return fmt.Errorf("answer %q doesn't match any option", p.Answer)
+		}
+	case KindNumeric:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(p.Answer), 64); err != nil {
+			return fmt.Errorf("answer %q is not a number", p.Answer)
+		}
+		if p.Tolerance < 0 {
+			return errors.New("tolerance is negative")
+		}
+	case KindAnyOf:
+		if len(p.accepted()) == 0 {
+			return errors.New("answers are empty")
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", p.Kind)
+	}
+
+	return nil
+}
+
+// Check reports whether answer is correct, text answers are
+// compared according to policy
+func (p Problem) Check(answer string, policy Policy) bool {
+	switch p.kind() {
+	case KindChoice:
+		i := p.optionIndex(answer, policy)
+		return i >= 0 && i == p.correctOption()
+	case KindNumeric:
+		got, err := strconv.ParseFloat(strings.TrimSpace(answer), 64)
+		if err != nil {
+			return false
+		}
+		want, err := strconv.ParseFloat(strings.TrimSpace(p.Answer), 64)
+		if err != nil {
+			return false
+		}
+		return math.Abs(got-want) <= p.Tolerance
+	case KindAnyOf:
+		for _, a := range p.accepted() {
+			if policy.Equal(answer, a) {
+				return true
+			}
+		}
+		return false
+	default:
+		return policy.Equal(answer, p.Answer)
+	}
+}
+
+// Prompt renders question for the terminal
+func (p Problem) Prompt() string {
+	switch p.kind() {
+	case KindChoice:
+		var b strings.Builder
+		fmt.Fprintln(&b, p.Question)
+		for i, opt := range p.Options {
+			fmt.Fprintf(&b, "  %c) %s\n", optionLetter(i), opt)
+		}
+		fmt.Fprint(&b, "Your choice: ")
+		return b.String()
+	case KindNumeric:
+		if p.Tolerance > 0 {
+			return fmt.Sprintf("%s (±%v) = ", p.Question, p.Tolerance)
+		}
+		return fmt.Sprintf("%s = ", p.Question)
+	default:
+		return fmt.Sprintf("%s = ", p.Question)
+	}
+}
+
+// ExpectedAnswer renders the correct answer to show it to user
+func (p Problem) ExpectedAnswer() string {
+	switch p.kind() {
+	case KindChoice:
+		if i := p.correctOption(); i >= 0 {
+			return fmt.Sprintf("%c) %s", optionLetter(i), p.Options[i])
+		}
+	case KindAnyOf:
+		return strings.Join(p.accepted(), ", ")
+	}
+	return p.Answer
+}
+
+// accepted returns all answers accepted by KindAnyOf question
+func (p Problem) accepted() []string {
+	answers := make([]string, 0, len(p.Answers)+1)
+	if strings.TrimSpace(p.Answer) != "" {
+		answers = append(answers, p.Answer)
+	}
+	for _, a := range p.Answers {
+		if strings.TrimSpace(a) != "" {
+			answers = append(answers, a)
+		}
+	}
+
+	return answers
+}
+
+// correctOption returns index of the correct option or -1
+func (p Problem) correctOption() int {
+	return p.optionIndex(p.Answer, DefaultPolicy)
+}
+
+// optionIndex returns index of option given by its letter
+// or its text compared according to policy, -1 is returned
+// if there is no such option
+func (p Problem) optionIndex(answer string, policy Policy) int {
+	letter := strings.ToLower(strings.TrimSpace(answer))
+	if len(letter) == 1 {
+		i := int(letter[0]) - 'a'
+		if i >= 0 && i < len(p.Options) {
+			return i
+		}
+	}
+
+	for i, opt := range p.Options {
+		if policy.Equal(answer, opt) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// optionLetter returns letter labeling option with index i
+func optionLetter(i int) rune {
+	return rune('a' + i)
+}
@@ -0,0 +1,92 @@
+package quiz
+
+import "testing"
+
+func TestProblemCheck(t *testing.T) {
+	choice := Problem{
+		Kind:     KindChoice,
+		Question: "Capital of France?",
+		Options:  []string{"London", "Paris", "Berlin"},
+		Answer:   "b",
+	}
+	numeric := Problem{
+		Kind:      KindNumeric,
+		Question:  "pi",
+		Answer:    "3.14",
+		Tolerance: 0.01,
+	}
+	anyOf := Problem{
+		Kind:     KindAnyOf,
+		Question: "Name a primary color",
+		Answers:  []string{"red", "green", "blue"},
+	}
+
+	tests := []struct {
+		name    string
+		problem Problem
+		answer  string
+		want    bool
+	}{
+		{"text correct", Problem{Question: "5+5", Answer: "10"}, "10", true},
+		{"text wrong", Problem{Question: "5+5", Answer: "10"}, "11", false},
+		{"choice letter", choice, "b", true},
+		{"choice upper letter", choice, "B", true},
+		{"choice option text", choice, "paris", true},
+		{"choice wrong letter", choice, "a", false},
+		{"choice out of range", choice, "z", false},
+		{"numeric exact", numeric, "3.14", true},
+		{"numeric within tolerance", numeric, "3.145", true},
+		{"numeric out of tolerance", numeric, "3.2", false},
+		{"numeric not a number", numeric, "pi", false},
+		{"any first", anyOf, "red", true},
+		{"any last", anyOf, "blue", true},
+		{"any wrong", anyOf, "yellow", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.problem.Check(test.answer, DefaultPolicy); got != test.want {
+				t.Errorf("Check(%q) = %v, want %v", test.answer, got, test.want)
+			}
+		})
+	}
+}
+
+func TestProblemValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		wantErr bool
+	}{
+		{"text", Problem{Question: "5+5", Answer: "10"}, false},
+		{"text without answer", Problem{Question: "5+5"}, true},
+		{"choice", Problem{Kind: KindChoice, Question: "q", Options: []string{"x", "y"}, Answer: "y"}, false},
+		{"choice single option", Problem{Kind: KindChoice, Question: "q", Options: []string{"x"}, Answer: "a"}, true},
+		{"choice unknown answer", Problem{Kind: KindChoice, Question: "q", Options: []string{"x", "y"}, Answer: "z"}, true},
+		{"numeric not a number", Problem{Kind: KindNumeric, Question: "q", Answer: "ten"}, true},
+		{"any without answers", Problem{Kind: KindAnyOf, Question: "q"}, true},
+		{"unknown kind", Problem{Kind: "essay", Question: "q", Answer: "a"}, true},
+		{"negative time limit", Problem{Question: "5+5", Answer: "10", TimeLimit: -1}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.problem.validate()
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestProblemPrompt(t *testing.T) {
+	p := Problem{
+		Kind:     KindChoice,
+		Question: "Capital of France?",
+		Options:  []string{"London", "Paris"},
+		Answer:   "Paris",
+	}
+	want := "Capital of France?\n  a) London\n  b) Paris\nYour choice: "
+
+	if got := p.Prompt(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package quiz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultProgress = "./quiz.progress.json"
+
+// savedProgress is the content of progress file, it keeps quiz
+// settings to resume the session without the problems file
+type savedProgress struct {
+	File           string        `json:"file"`
+	FileHash       string        `json:"file_hash"`
+	Problems       []Problem     `json:"problems"`
+	Policy         Policy        `json:"policy"`
+	Limit          time.Duration `json:"limit"`
+	QuestionLimit  time.Duration `json:"question_limit"`
+	Shuffle        bool          `json:"shuffle"`
+	ShuffleOptions bool          `json:"shuffle_options"`
+	Count          int           `json:"count"`
+	HintPenalty    float64       `json:"hint_penalty"`
+	Generated      bool          `json:"generated"`
+	Progress       Progress      `json:"progress"`
+}
+
+// quiz returns the quiz the progress was saved from
+func (sp savedProgress) quiz() Quiz {
+	return Quiz{
+		Problems:       sp.Problems,
+		Policy:         sp.Policy,
+		Limit:          sp.Limit,
+		QuestionLimit:  sp.QuestionLimit,
+		Shuffle:        sp.Shuffle,
+		ShuffleOptions: sp.ShuffleOptions,
+		Count:          sp.Count,
+		HintPenalty:    sp.HintPenalty,
+		Seed:           sp.Progress.Seed,
+		Generated:      sp.Generated,
+	}
+}
+
+// saveProgress writes progress of the interrupted session to file
+func saveProgress(fileName string, sp savedProgress) error {
+	buf, err := json.MarshalIndent(sp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, buf, 0600)
+}
+
+// loadProgress reads progress saved by saveProgress
+func loadProgress(fileName string) (savedProgress, error) {
+	var sp savedProgress
+
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return sp, err
+	}
+
+	if err := json.Unmarshal(buf, &sp); err != nil {
+		return sp, fmt.Errorf("parse %s: %w", fileName, err)
+	}
+
+	return sp, nil
+}
+
+// saveSession saves progress of the session interrupted by signal,
+// it reports whether progress was saved
+func (app *appEnv) saveSession(s *Session) (bool, error) {
+	reason, _ := s.Finished()
+	if app.progress == "" || reason != ReasonCanceled {
+		return false, nil
+	}
+
+	q := s.Quiz()
+	sp := savedProgress{
+		File:           app.fileName,
+		FileHash:       app.fileHash,
+		Problems:       q.Problems,
+		Policy:         q.Policy,
+		Limit:          q.Limit,
+		QuestionLimit:  q.QuestionLimit,
+		Shuffle:        q.Shuffle,
+		ShuffleOptions: q.ShuffleOptions,
+		Count:          q.Count,
+		HintPenalty:    q.HintPenalty,
+		Generated:      q.Generated,
+		Progress:       s.Progress(),
+	}
+	if err := saveProgress(app.progress, sp); err != nil {
+		return false, fmt.Errorf("save progress: %w", err)
+	}
+
+	resume := "-resume"
+	if app.progress != defaultProgress {
+		resume = fmt.Sprintf("-resume -progress %s", app.progress)
+	}
+	fmt.Fprintf(app.term.Out, "Progress is saved to %s, run with %s to continue.\n", app.progress, resume)
+	return true, nil
+}
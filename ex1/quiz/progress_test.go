@@ -0,0 +1,39 @@
+package quiz
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProgressFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "progress.json")
+
+	app := appEnv{fileName: "problems.csv", fileHash: "hash", progress: fileName}
+	app.term.Out = io.Discard
+
+	quiz := Quiz{Problems: []Problem{{Question: "5+5", Answer: "10"}, {Question: "1+1", Answer: "2"}}, Limit: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := quiz.Start(ctx)
+	s.Submit("10")
+	cancel()
+	waitFinished(t, s, ReasonCanceled)
+
+	saved, err := app.saveSession(s)
+	if err != nil || !saved {
+		t.Fatalf("got saved %v and error %v, want saved progress", saved, err)
+	}
+
+	resumed := appEnv{progress: fileName}
+	if err := resumed.fromArgs([]string{"-progress", fileName, "-resume"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resumed.fileName != "problems.csv" || resumed.saved.Progress.Index != 1 {
+		t.Errorf("got file %q and progress %+v, want the second problem of problems.csv", resumed.fileName, resumed.saved.Progress)
+	}
+	if q := resumed.saved.quiz(); q.Limit != time.Minute || len(q.Problems) != 2 {
+		t.Errorf("got quiz %+v, want the saved one", q)
+	}
+}
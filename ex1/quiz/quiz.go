@@ -1,24 +1,46 @@
 package quiz
 
 import (
-	"encoding/csv"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
 
 func CLI(args []string) int {
-	var app appEnv
+	if len(args) > 0 && args[0] == "stats" {
+		return statsCLI(args[1:])
+	}
+
+	app := appEnv{
+		term: NewTerminal(),
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		app.serve = true
+		args = args[1:]
+	}
 
 	err := app.fromArgs(args)
 	if err != nil {
 		return 2
 	}
 
-	if err = app.run(); err != nil {
+	// Cancel the quiz on interrupt signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = app.run(ctx)
+	if errors.Is(err, errNotPassed) {
+		return exitNotPassed
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
 		return 1
 	}
@@ -29,121 +51,432 @@ func CLI(args []string) int {
 const (
 	defaultTimeLimit = 30
 	defaultProblems  = "./problems.csv"
+	defaultAddr      = ":8080"
+	// defaultHintPenalty halves the point of correct answer
+	// given after the hint
+	defaultHintPenalty = 0.5
 )
 
 type appEnv struct {
-	limit    int64
-	csv      string
-	csvFile  *os.File
-	problems []problem
+	limit            int64
+	questionLimitSec int64
+	fileName         string
+	fileHash         string
+	format           string
+	history          string
+	historyMu        sync.Mutex
+	problems         []Problem
+	policy           Policy
+	shuffle          bool
+	shuffleOptions   bool
+	count            int
+	seed             int64
+	generate         string
+	templates        string
+	generated        bool
+	progress         string
+	hintPenalty      float64
+	resume           bool
+	saved            *savedProgress
+	practice         bool
+	web              bool
+	addr             string
+	serve            bool
+	players          int
+	report           string
+	reportFile       string
+	passThreshold    float64
+	term             Terminal
 }
 
-type problem struct {
-	question string
-	answer   string
-}
+// errNotPassed is returned when the score is below pass threshold
+var errNotPassed = errors.New("score is below pass threshold")
+
+// exitNotPassed is the exit status of the quiz which wasn't passed
+const exitNotPassed = 3
 
 func (app *appEnv) fromArgs(args []string) error {
 	fl := flag.NewFlagSet("quiz", flag.ContinueOnError)
 	fl.StringVar(
-		&app.csv, "csv", defaultProblems, "a csv file in the format of \"question,answer\"",
+		&app.fileName, "file", defaultProblems, "a problems file in csv, json, jsonl or yaml format",
+	)
+	fl.StringVar(
+		&app.fileName, "csv", defaultProblems, "a csv file in the format of \"question,answer\" (alias of -file)",
+	)
+	fl.StringVar(
+		&app.format, "format", "", "format of problems file: csv, json, jsonl or yaml (detected by extension if empty)",
 	)
 	fl.Int64Var(
-		&app.limit, "limit", defaultTimeLimit, "the time limit for the quiz in seconds",
+		&app.limit, "limit", defaultTimeLimit, "the time limit for the quiz in seconds, 0 means no limit",
+	)
+	fl.Int64Var(
+		&app.questionLimitSec, "question-limit", 0, "the default time limit for a single problem in seconds, 0 means no limit",
+	)
+	fl.StringVar(
+		&app.history, "history", defaultHistory, "a database file to save results history to, empty value disables history",
+	)
+	fl.BoolVar(
+		&app.shuffle, "shuffle", false, "ask problems in random order",
+	)
+	fl.BoolVar(
+		&app.shuffleOptions, "shuffle-options", false, "show multiple choice options in random order",
+	)
+	fl.IntVar(
+		&app.count, "n", 0, fmt.Sprintf("the number of randomly picked problems to ask, 0 means all problems (%d generated ones)", defaultGenerated),
+	)
+	fl.Int64Var(
+		&app.seed, "seed", 0, "the seed to reproduce random order or generated problems of a previous run, 0 means new random seed",
+	)
+	fl.Float64Var(
+		&app.hintPenalty, "hint-penalty", defaultHintPenalty, fmt.Sprintf("the number of points subtracted for correct answer after the hint, type %s to show the hint", HintCommand),
+	)
+	fl.BoolVar(
+		&app.term.Review, "review", app.term.Review, "list wrong answers with explanations after the quiz",
+	)
+	fl.StringVar(
+		&app.progress, "progress", defaultProgress, fmt.Sprintf("a file to save progress to on interrupt instead of finishing the quiz, type %s to pause instead, empty value disables saving", PauseCommand),
+	)
+	fl.BoolVar(
+		&app.resume, "resume", false, "continue the quiz saved to progress file, other quiz flags are ignored",
+	)
+	fl.StringVar(
+		&app.generate, "generate", "", "generate problems from the template instead of problems file, e.g. \"{a:1..20} * {b:1..12}\"",
+	)
+	fl.StringVar(
+		&app.templates, "templates", "", "generate problems from the file with a template per line instead of problems file",
+	)
+	fl.BoolVar(
+		&app.practice, "practice", false, "ask only problems due for spaced repetition review, requires history",
+	)
+	fl.BoolVar(
+		&app.web, "web", false, "serve the quiz over HTTP instead of the terminal",
+	)
+	fl.StringVar(
+		&app.addr, "addr", defaultAddr, "the address to listen on in web and serve modes",
+	)
+	fl.IntVar(
+		&app.players, "players", defaultPlayers, "the number of players to wait for before the first question in serve mode",
+	)
+	fl.StringVar(
+		&app.report, "report", "", "write machine-readable results in json, junit or csv format",
+	)
+	fl.StringVar(
+		&app.reportFile, "report-file", "", "a file to write the report to, empty value means standard output",
+	)
+	fl.Float64Var(
+		&app.passThreshold, "pass-threshold", 0, fmt.Sprintf("the percentage of correct answers to pass the quiz, exit status is %d if it is not passed", exitNotPassed),
+	)
+	fl.BoolVar(
+		&app.policy.FoldCase, "fold-case", DefaultPolicy.FoldCase, "ignore letter case of answers",
+	)
+	fl.BoolVar(
+		&app.policy.TrimSpace, "trim", DefaultPolicy.TrimSpace, "ignore leading, trailing and repeated whitespace in answers",
+	)
+	fl.BoolVar(
+		&app.policy.Normalize, "normalize", DefaultPolicy.Normalize, "apply Unicode NFKC normalization to answers",
+	)
+	fl.IntVar(
+		&app.policy.MaxDistance, "distance", DefaultPolicy.MaxDistance, "the maximum number of typos (Levenshtein distance) allowed in text answers",
+	)
+	fl.BoolVar(
+		&app.policy.Numeric, "numeric", DefaultPolicy.Numeric, "treat equal numbers as equal answers, e.g. \"10\" and \"10.0\"",
 	)
 
 	if err := fl.Parse(args); err != nil {
 		return err
 	}
 
-	file, err := os.Open(app.csv)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "got bad output type: %v\n", app.csv)
+	if app.limit < 0 {
+		fmt.Fprintf(os.Stderr, "got bad limit: %v\n", app.limit)
 		fl.Usage()
 		return flag.ErrHelp
 	}
-	app.csvFile = file
+
+	if _, ok := reporters[app.report]; app.report != "" && !ok {
+		fmt.Fprintf(os.Stderr, "got bad report format: %v\n", app.report)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.web && app.serve {
+		fmt.Fprintln(os.Stderr, "web mode is not supported in serve mode")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.players < 1 {
+		fmt.Fprintf(os.Stderr, "got bad number of players: %v\n", app.players)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if (app.web || app.serve) && app.report != "" {
+		fmt.Fprintln(os.Stderr, "reports are not supported in web and serve modes")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.hintPenalty < 0 || app.hintPenalty > 1 {
+		fmt.Fprintf(os.Stderr, "got bad hint penalty: %v\n", app.hintPenalty)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.passThreshold < 0 || app.passThreshold > 100 {
+		fmt.Fprintf(os.Stderr, "got bad pass threshold: %v\n", app.passThreshold)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.report != "" && app.reportFile == "" {
+		// keep standard output clean for the report
+		app.term.Out = os.Stderr
+		app.term.Live = isTerminal(os.Stderr)
+	}
+
+	if app.resume && (app.web || app.serve || app.practice) {
+		fmt.Fprintln(os.Stderr, "resume is supported only in terminal mode")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.resume && app.progress == "" {
+		fmt.Fprintln(os.Stderr, "resume requires -progress file")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.practice && app.history == "" {
+		fmt.Fprintln(os.Stderr, "practice mode requires history")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.count < 0 {
+		fmt.Fprintf(os.Stderr, "got bad number of problems: %v\n", app.count)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.questionLimitSec < 0 {
+		fmt.Fprintf(os.Stderr, "got bad question limit: %v\n", app.questionLimitSec)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.policy.MaxDistance < 0 {
+		fmt.Fprintf(os.Stderr, "got bad distance: %v\n", app.policy.MaxDistance)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.generate != "" && app.templates != "" {
+		fmt.Fprintln(os.Stderr, "use either -generate or -templates")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	if app.resume {
+		sp, err := loadProgress(app.progress)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "got bad progress file: %v\n", err)
+			return err
+		}
+		app.saved = &sp
+		app.fileName, app.fileHash = sp.File, sp.FileHash
+		return nil
+	}
+
+	if app.generate != "" || app.templates != "" {
+		return app.generateProblems()
+	}
+
+	problems, err := LoadFile(app.fileName, app.format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "got bad problems file: %v\n", err)
+		return err
+	}
+	app.problems = problems
+
+	hash, err := hashFile(app.fileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "got bad problems file: %v\n", err)
+		return err
+	}
+	app.fileHash = hash
 
 	return nil
 }
 
-func (app *appEnv) run() error {
-	score := 0
+// generateProblems replaces problems file with problems generated
+// from templates, the seed is picked here to show it after the quiz
+func (app *appEnv) generateProblems() error {
+	var templates []Template
+	if app.templates != "" {
+		t, err := LoadTemplates(app.templates)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "got bad templates file: %v\n", err)
+			return err
+		}
+		templates = t
 
-	errCh := make(chan error)
-	answerCh := make(chan string)
+		hash, err := hashFile(app.templates)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "got bad templates file: %v\n", err)
+			return err
+		}
+		app.fileName, app.fileHash = app.templates, hash
+	} else {
+		t, err := ParseTemplate(app.generate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "got bad template: %v\n", err)
+			return err
+		}
+		templates = []Template{t}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		sum := sha256.Sum256([]byte(app.generate))
+		app.fileName, app.fileHash = app.generate, hex.EncodeToString(sum[:])
+	}
 
-	problemNum, err := app.parseProblems()
+	if app.count == 0 {
+		app.count = defaultGenerated
+	}
+	if app.seed == 0 {
+		app.seed = time.Now().UnixNano()
+	}
+
+	problems, err := Generate(templates, app.count, app.seed)
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "got bad template: %v\n", err)
 		return err
 	}
+	app.problems = problems
+	app.count = 0
+	app.generated = true
+
+	return nil
+}
+
+func (app *appEnv) run(ctx context.Context) error {
+	quiz := Quiz{
+		Problems:       app.problems,
+		Policy:         app.policy,
+		Limit:          time.Second * time.Duration(app.limit),
+		QuestionLimit:  time.Second * time.Duration(app.questionLimitSec),
+		Shuffle:        app.shuffle,
+		ShuffleOptions: app.shuffleOptions,
+		Count:          app.count,
+		Seed:           app.seed,
+		HintPenalty:    app.hintPenalty,
+		Generated:      app.generated,
+	}
 
-	timer := time.NewTimer(time.Second * time.Duration(app.limit))
-	defer timer.Stop()
+	if app.web {
+		return app.runWeb(ctx, quiz)
+	}
+
+	if app.serve {
+		return app.runServe(ctx, quiz)
+	}
 
-	for i, problem := range app.problems {
-		fmt.Printf("Problem #%v: %v = ", i+1, problem.question)
+	if app.practice {
+		return app.runPractice(ctx, quiz)
+	}
 
-		//TODO: use single goroutine and in/out channels instead creating goroutine for every problem
-		go getAnswer(answerCh, errCh)
+	var s *Session
+	if app.saved != nil {
+		var err error
+		s, err = app.saved.quiz().Resume(ctx, app.saved.Progress)
+		if err != nil {
+			return err
+		}
+	} else {
+		s = quiz.Start(ctx)
+	}
+	if err := app.term.Run(ctx, s); err != nil {
+		return err
+	}
 
-		select {
-		case answer := <-answerCh:
-			if answer == problem.answer {
-				score++
-			}
-		case <-quit:
-			fmt.Printf("\nProgram interrupted. You scored %v out of %v.\n", score, problemNum)
-			fmt.Print("Press enter to quit...")
-			<-errCh
-			return nil
-		case err := <-errCh:
+	saved, err := app.saveSession(s)
+	if err != nil || saved {
+		return err
+	}
+	if app.saved != nil {
+		if err := os.Remove(app.progress); err != nil && !errors.Is(err, os.ErrNotExist) {
 			return err
-		case <-timer.C:
-			fmt.Printf("\nTime ran out (%vs). You scored %v out of %v.\n", app.limit, score, problemNum)
-			fmt.Print("Press enter to quit...")
-			<-errCh
-			return nil
 		}
 	}
 
-	fmt.Printf("You scored %v out of %v.\n", score, problemNum)
-	fmt.Print("Press enter to quit...")
-	fmt.Scanf("\n")
+	if err := app.record(s); err != nil {
+		return err
+	}
+
+	return app.finish(s)
+}
+
+// finish writes report of the finished session and checks it
+// against pass threshold
+func (app *appEnv) finish(s *Session) error {
+	r := NewReport(s, app.fileName, app.passThreshold)
+
+	if app.report != "" {
+		if err := app.writeReport(r); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+	}
+
+	if !r.Passed {
+		return errNotPassed
+	}
 
 	return nil
 }
 
-func getAnswer(answerCh chan string, errCh chan error) {
-	var answer string
-	n, err := fmt.Scanf("%s\n", &answer)
+// writeReport writes report to the report file or standard output
+func (app *appEnv) writeReport(r Report) error {
+	write := reporters[app.report]
+	if app.reportFile == "" {
+		return write(os.Stdout, r)
+	}
+
+	f, err := os.Create(app.reportFile)
 	if err != nil {
-		errCh <- err
-		return
+		return err
 	}
-	if n != 1 {
-		errCh <- fmt.Errorf("wrong number of arguments: %v instead of 1", n)
-		return
+
+	if err := write(f, r); err != nil {
+		f.Close()
+		return err
 	}
-	answerCh <- answer
+
+	return f.Close()
 }
 
-func (app *appEnv) parseProblems() (int, error) {
-	r := csv.NewReader(app.csvFile)
-	records, err := r.ReadAll()
-	if err != nil {
-		return 0, err
+// record saves finished session to history, it is no-op if
+// history is disabled
+func (app *appEnv) record(s *Session) error {
+	if app.history == "" {
+		return nil
 	}
 
-	problemNum := len(records)
-	app.problems = make([]problem, problemNum)
+	// web sessions may finish simultaneously
+	app.historyMu.Lock()
+	defer app.historyMu.Unlock()
 
-	for i, record := range records {
-		app.problems[i] = problem{question: record[0], answer: record[1]}
+	h, err := NewBoltHistory(app.history)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
 	}
+	defer h.Close()
 
-	return problemNum, nil
+	a := newAttempt(s, app.fileName, app.fileHash)
+	if _, err := h.Store(a); err != nil {
+		return fmt.Errorf("save history: %w", err)
+	}
+	// keep practice schedule up to date with regular runs
+	if err := updateCards(h, a); err != nil {
+		return fmt.Errorf("save card: %w", err)
+	}
+
+	return nil
 }
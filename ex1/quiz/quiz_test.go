@@ -1,3 +1,60 @@
 package quiz
 
-// TODO: write tests
+import (
+	"testing"
+	"time"
+)
+
+func TestFromArgs(t *testing.T) {
+	var app appEnv
+	err := app.fromArgs([]string{"-file", "../problems.csv", "-limit", "10", "-question-limit", "2", "-distance", "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(app.problems) != 12 {
+		t.Errorf("got %d problems, want 12", len(app.problems))
+	}
+	if app.limit != 10 || app.questionLimitSec != 2 {
+		t.Errorf("got limits %v and %v, want 10 and 2", app.limit, app.questionLimitSec)
+	}
+
+	want := Policy{FoldCase: true, TrimSpace: true, MaxDistance: 1}
+	if app.policy != want {
+		t.Errorf("got policy %+v, want %+v", app.policy, want)
+	}
+}
+
+func TestFromArgsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"missing file", []string{"-file", "missing.csv"}},
+		{"unknown format", []string{"-file", "../problems.csv", "-format", "xml"}},
+		{"negative limit", []string{"-file", "../problems.csv", "-limit", "-1"}},
+		{"negative distance", []string{"-file", "../problems.csv", "-distance", "-1"}},
+		{"unknown report", []string{"-file", "../problems.csv", "-report", "xml"}},
+		{"report in web mode", []string{"-file", "../problems.csv", "-web", "-report", "json"}},
+		{"bad pass threshold", []string{"-file", "../problems.csv", "-pass-threshold", "101"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var app appEnv
+			if err := app.fromArgs(test.args); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestQuizQuestionLimit(t *testing.T) {
+	q := Quiz{QuestionLimit: 5 * time.Second}
+
+	if got := q.questionLimit(Problem{}); got != 5*time.Second {
+		t.Errorf("got default limit %v, want 5s", got)
+	}
+	if got := q.questionLimit(Problem{TimeLimit: 2}); got != 2*time.Second {
+		t.Errorf("got problem limit %v, want 2s", got)
+	}
+}
@@ -0,0 +1,206 @@
+package quiz
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Status values of a single problem in reports
+const (
+	StatusCorrect    = "correct"
+	StatusWrong      = "wrong"
+	StatusTimedOut   = "timed_out"
+	StatusUnanswered = "unanswered"
+)
+
+// Report is machine-readable result of a finished session,
+// Duration is the quiz time used without pauses and interruptions
+type Report struct {
+	File      string         `json:"file"`
+	Seed      int64          `json:"seed"`
+	StartedAt time.Time      `json:"started_at"`
+	Duration  time.Duration  `json:"duration"`
+	Reason    string         `json:"reason"`
+	Score     Score          `json:"score"`
+	Threshold float64        `json:"threshold"`
+	Passed    bool           `json:"passed"`
+	Problems  []ReportResult `json:"problems"`
+}
+
+// ReportResult is the result of a single problem in Report,
+// problems left unanswered have zero duration
+type ReportResult struct {
+	Question string        `json:"question"`
+	Answer   string        `json:"answer"`
+	Expected string        `json:"expected"`
+	Status   string        `json:"status"`
+	Hinted   bool          `json:"hinted,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// NewReport builds report of the finished session, it is passed
+// if the percentage of points is at least threshold
+func NewReport(s *Session, file string, threshold float64) Report {
+	reason, _ := s.Finished()
+	score := s.Score()
+	r := Report{
+		File:      file,
+		Seed:      s.Seed(),
+		StartedAt: s.StartedAt(),
+		Duration:  s.Elapsed(),
+		Reason:    reason.String(),
+		Score:     score,
+		Threshold: threshold,
+		Passed:    passed(score, threshold),
+	}
+
+	results := s.Results()
+	for i, p := range s.Problems() {
+		rr := ReportResult{
+			Question: p.Question,
+			Expected: p.ExpectedAnswer(),
+			Status:   StatusUnanswered,
+		}
+		if i < len(results) {
+			res := results[i]
+			rr.Answer = res.Answer
+			rr.Hinted = res.Hinted
+			rr.Duration = res.Duration
+			switch {
+			case res.Correct:
+				rr.Status = StatusCorrect
+			case res.TimedOut:
+				rr.Status = StatusTimedOut
+			default:
+				rr.Status = StatusWrong
+			}
+		}
+		r.Problems = append(r.Problems, rr)
+	}
+
+	return r
+}
+
+// passed reports whether the percentage of points is at least
+// threshold, hint penalties are counted
+func passed(score Score, threshold float64) bool {
+	if score.Total == 0 {
+		return threshold <= 0
+	}
+	return score.Points/float64(score.Total)*100 >= threshold
+}
+
+// reporters writes report in the format by its name
+var reporters = map[string]func(w io.Writer, r Report) error{
+	"json":  writeJSONReport,
+	"junit": writeJUnitReport,
+	"csv":   writeCSVReport,
+}
+
+// writeJSONReport writes report as indented JSON object
+func writeJSONReport(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// junitSuite is JUnit XML test suite, every problem is a test case
+type junitSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnitReport writes report as JUnit XML, wrong and timed out
+// answers are failures, unanswered problems are skipped
+func writeJUnitReport(w io.Writer, r Report) error {
+	suite := junitSuite{
+		Name:      r.File,
+		Tests:     len(r.Problems),
+		Time:      seconds(r.Duration),
+		Timestamp: r.StartedAt.Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{Name: "seed", Value: strconv.FormatInt(r.Seed, 10)},
+			{Name: "reason", Value: r.Reason},
+			{Name: "threshold", Value: strconv.FormatFloat(r.Threshold, 'f', -1, 64)},
+			{Name: "passed", Value: strconv.FormatBool(r.Passed)},
+		},
+	}
+
+	for i, p := range r.Problems {
+		c := junitCase{
+			Name:      fmt.Sprintf("Problem #%d: %s", i+1, p.Question),
+			ClassName: r.File,
+			Time:      seconds(p.Duration),
+		}
+		switch p.Status {
+		case StatusWrong:
+			suite.Failures++
+			c.Failure = &junitMessage{Message: fmt.Sprintf("expected %s, got %s", p.Expected, p.Answer)}
+		case StatusTimedOut:
+			suite.Failures++
+			c.Failure = &junitMessage{Message: "timed out"}
+		case StatusUnanswered:
+			suite.Skipped++
+			c.Skipped = &junitMessage{Message: "not answered"}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeCSVReport writes report as CSV with a row per problem
+func writeCSVReport(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"number", "question", "answer", "expected", "status", "duration"})
+	for i, p := range r.Problems {
+		cw.Write([]string{
+			strconv.Itoa(i + 1), p.Question, p.Answer, p.Expected, p.Status, seconds(p.Duration),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// seconds formats duration in seconds with millisecond precision
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
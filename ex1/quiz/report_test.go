@@ -0,0 +1,142 @@
+package quiz
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+// reportSession returns session with correct, wrong and
+// unanswered problems
+func reportSession(t *testing.T) *Session {
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+			{Question: "2+2", Answer: "4"},
+		},
+		Policy: DefaultPolicy,
+	}
+	s := quiz.Start(context.Background())
+	for _, answer := range []string{"10", "3"} {
+		if _, err := s.Submit(answer); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	s.Stop()
+	return s
+}
+
+func TestNewReportResumedDuration(t *testing.T) {
+	quiz := Quiz{Problems: []Problem{{Question: "5+5", Answer: "10"}}, Policy: DefaultPolicy}
+	s, err := quiz.Resume(context.Background(), Progress{
+		StartedAt: time.Now().Add(-time.Hour),
+		Elapsed:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Stop()
+
+	// time between interrupt and resume isn't counted
+	r := NewReport(s, "problems.csv", 0)
+	if r.Duration < 2*time.Second || r.Duration > time.Minute {
+		t.Errorf("got duration %v, want about 2s", r.Duration)
+	}
+	if again := NewReport(s, "problems.csv", 0); again.Duration != r.Duration {
+		t.Errorf("got duration %v of finished session, want %v", again.Duration, r.Duration)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	s := reportSession(t)
+
+	tests := []struct {
+		threshold float64
+		passed    bool
+	}{
+		{0, true},
+		{33, true},
+		{34, false},
+		{100, false},
+	}
+	for _, test := range tests {
+		r := NewReport(s, "problems.csv", test.threshold)
+		if r.Passed != test.passed {
+			t.Errorf("threshold %v: got passed %v, want %v", test.threshold, r.Passed, test.passed)
+		}
+	}
+
+	r := NewReport(s, "problems.csv", 0)
+	if r.Reason != "stopped" {
+		t.Errorf("got reason %q, want stopped", r.Reason)
+	}
+	want := []string{StatusCorrect, StatusWrong, StatusUnanswered}
+	if len(r.Problems) != len(want) {
+		t.Fatalf("got %d problems, want %d", len(r.Problems), len(want))
+	}
+	for i, status := range want {
+		if r.Problems[i].Status != status {
+			t.Errorf("problem #%d: got status %q, want %q", i+1, r.Problems[i].Status, status)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	r := NewReport(reportSession(t), "problems.csv", 50)
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeJSONReport(&buf, r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var got Report
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Score != r.Score || got.Passed || len(got.Problems) != 3 {
+			t.Errorf("got report %+v, want %+v", got, r)
+		}
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeJUnitReport(&buf, r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var got junitSuite
+		if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Tests != 3 || got.Failures != 1 || got.Skipped != 1 {
+			t.Errorf("got %d tests, %d failures, %d skipped, want 3, 1 and 1", got.Tests, got.Failures, got.Skipped)
+		}
+		if got.Cases[1].Failure == nil || got.Cases[1].Failure.Message != "expected 2, got 3" {
+			t.Errorf("got failure %+v, want expected 2, got 3", got.Cases[1].Failure)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeCSVReport(&buf, r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(records) != 4 {
+			t.Fatalf("got %d records, want header and 3 problems", len(records))
+		}
+		if records[2][4] != StatusWrong {
+			t.Errorf("got status %q, want %q", records[2][4], StatusWrong)
+		}
+	})
+}
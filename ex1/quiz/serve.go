@@ -0,0 +1,550 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPlayers is the number of players the game waits for
+	// before the first question
+	defaultPlayers = 2
+	// writeTimeout keeps slow client from blocking the game
+	writeTimeout = 5 * time.Second
+	// maxNameLength limits player name
+	maxNameLength = 32
+)
+
+// ErrGameStarted is returned to clients joining the started game
+var ErrGameStarted = errors.New("game already started")
+
+// Game runs quiz for several players connected over line-based
+// TCP protocol. Every question is sent to all players at the same
+// time, the round lasts until everybody answered or the question
+// time limit is exceeded.
+//
+// Every player answers in its own Session of the quiz, so answers
+// are graded and timed the same way as in other modes. Session is
+// paused while its player waits for others, so the quiz time limit
+// counts only the time spent on answers.
+//
+// Server messages are:
+//
+//	WELCOME <text>            asks for the player name
+//	JOINED <name>             confirms the name
+//	WAITING <joined>/<needed> game waits for more players
+//	QUESTION <n>/<total> <limit>: <question>
+//	OPTION <letter>) <text>   multiple choice option of the question
+//	ACCEPTED <time>           answer is received
+//	RESULT <correct|wrong|timed_out> <expected answer>
+//	LEADERBOARD               followed by RANK lines and END
+//	RANK <n> <name> <points> <time>
+//	FINISHED <reason>         followed by the final leaderboard
+//	ERROR <text>
+//
+// Client sends its name and then one line with the answer to
+// every question.
+type Game struct {
+	Quiz Quiz
+	// Players is the number of players to wait for before
+	// the first question
+	Players int
+	// Out receives game log and leaderboard
+	Out io.Writer
+
+	mu      sync.Mutex
+	players []*player
+	started bool
+
+	joined  chan *player
+	left    chan *player
+	answers chan answer
+	events  chan playerEvent
+}
+
+// player is a client connected to the game, its session is
+// started with the first question
+type player struct {
+	name    string
+	conn    net.Conn
+	mu      sync.Mutex
+	session *Session
+	gone    bool
+}
+
+// playerEvent is an event of player's session
+type playerEvent struct {
+	player *player
+	Event
+}
+
+// answer is a line received from player
+type answer struct {
+	player *player
+	text   string
+	at     time.Time
+}
+
+// Standing is player's position in the leaderboard, time is the
+// total time spent on answers
+type Standing struct {
+	Name  string
+	Score Score
+	Time  time.Duration
+}
+
+// NewGame creates game of the quiz for given number of players
+func NewGame(q Quiz, players int, out io.Writer) *Game {
+	return &Game{
+		Quiz:    q,
+		Players: players,
+		Out:     out,
+		joined:  make(chan *player),
+		left:    make(chan *player),
+		answers: make(chan answer),
+		events:  make(chan playerEvent),
+	}
+}
+
+// Serve accepts players on l and runs the game until all problems
+// are asked, time is up or ctx is canceled, l is closed on return
+func (g *Game) Serve(ctx context.Context, l net.Listener) (Reason, error) {
+	// handlers are waited for after they are canceled
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer l.Close()
+
+	acceptErr := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				acceptErr <- err
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				g.handle(ctx, conn)
+			}()
+		}
+	}()
+
+	if err := g.wait(ctx, acceptErr); err != nil {
+		return ReasonCanceled, err
+	}
+
+	reason := g.play(ctx)
+	g.finish(reason)
+	return reason, nil
+}
+
+// wait accepts players until there are enough of them
+func (g *Game) wait(ctx context.Context, acceptErr <-chan error) error {
+	fmt.Fprintf(g.Out, "Waiting for %d players...\n", g.Players)
+
+	for {
+		select {
+		case p := <-g.joined:
+			fmt.Fprintf(g.Out, "%s joined.\n", p.name)
+			n := len(g.active())
+			g.broadcast("WAITING %d/%d", n, g.Players)
+			if n >= g.Players {
+				g.mu.Lock()
+				g.started = true
+				g.mu.Unlock()
+				return nil
+			}
+		case p := <-g.left:
+			fmt.Fprintf(g.Out, "%s left.\n", p.name)
+		case a := <-g.answers:
+			a.player.send("ERROR game hasn't started")
+		case err := <-acceptErr:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// play starts sessions of all players, asks all problems and
+// returns the reason the game finished
+func (g *Game) play(ctx context.Context) Reason {
+	players := g.active()
+	if len(players) == 0 {
+		return ReasonStopped
+	}
+
+	// all sessions ask the same problems
+	q := g.Quiz
+	q.Seed = q.newSeed()
+	g.mu.Lock()
+	for _, pl := range players {
+		pl.session = q.Start(ctx)
+		go g.forward(ctx, pl)
+	}
+	g.mu.Unlock()
+
+	problems := players[0].session.Problems()
+	for i, p := range problems {
+		reason := g.round(ctx, i, len(problems), p)
+		g.printLeaderboard()
+		if reason != 0 {
+			return reason
+		}
+		if i+1 < len(problems) && len(g.playing()) == 0 {
+			return ReasonTimeUp
+		}
+	}
+
+	return ReasonCompleted
+}
+
+// forward sends events of player's session to the game until the
+// session or ctx is done
+func (g *Game) forward(ctx context.Context, pl *player) {
+	for e := range pl.session.Events() {
+		select {
+		case g.events <- playerEvent{player: pl, Event: e}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// round resumes sessions waiting for problem and collects answers
+// of their players, it returns non-zero reason if the game should
+// be finished
+func (g *Game) round(ctx context.Context, i, total int, p Problem) Reason {
+	asked := time.Now()
+	players := g.playing()
+	for _, pl := range players {
+		pl.session.Resume()
+	}
+
+	limit := g.Quiz.questionLimit(p)
+	for _, pl := range players {
+		pl.send("QUESTION %d/%d %vs: %s", i+1, total, limit.Seconds(), p.Question)
+		if p.kind() == KindChoice {
+			for j, opt := range p.Options {
+				pl.send("OPTION %c) %s", optionLetter(j), opt)
+			}
+		}
+	}
+
+	// done are players which answered, timed out or finished
+	// their sessions
+	done := make(map[*player]bool)
+	var reason Reason
+	for reason == 0 && !g.allDone(done) {
+		select {
+		case a := <-g.answers:
+			if a.at.Before(asked) {
+				a.player.send("ERROR answer is too late")
+				continue
+			}
+			if done[a.player] {
+				a.player.send("ERROR already answered")
+				continue
+			}
+
+			res, err := a.player.session.Submit(a.text)
+			if err != nil {
+				// timed out and finished sessions are handled
+				// by their events
+				a.player.send("ERROR %v", err)
+				continue
+			}
+			a.player.session.Pause()
+			done[a.player] = true
+			a.player.send("ACCEPTED %v", res.Duration.Round(time.Millisecond))
+		case e := <-g.events:
+			switch {
+			case e.Kind == EventTimeout && e.Index == i:
+				e.player.session.Pause()
+				done[e.player] = true
+			case e.Kind == EventFinished:
+				done[e.player] = true
+			}
+		case pl := <-g.joined:
+			pl.send("ERROR %v", ErrGameStarted)
+			pl.conn.Close()
+		case pl := <-g.left:
+			fmt.Fprintf(g.Out, "%s left.\n", pl.name)
+			if pl.session != nil {
+				pl.session.Stop()
+			}
+			if len(g.active()) == 0 {
+				reason = ReasonStopped
+			}
+		case <-ctx.Done():
+			reason = ReasonCanceled
+		}
+	}
+
+	for _, pl := range players {
+		status := StatusTimedOut
+		if results := pl.session.Results(); i < len(results) {
+			switch res := results[i]; {
+			case res.Correct:
+				status = StatusCorrect
+			case !res.TimedOut:
+				status = StatusWrong
+			}
+		}
+		pl.send("RESULT %s %s", status, p.ExpectedAnswer())
+	}
+	g.sendLeaderboard()
+
+	return reason
+}
+
+// allDone reports whether every player still playing is done with
+// the round
+func (g *Game) allDone(done map[*player]bool) bool {
+	for _, p := range g.playing() {
+		if !done[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// finish sends final results and disconnects players
+func (g *Game) finish(reason Reason) {
+	fmt.Fprintf(g.Out, "Game finished: %v.\n", reason)
+
+	g.broadcast("FINISHED %v", reason)
+	g.sendLeaderboard()
+
+	for _, p := range g.active() {
+		p.conn.Close()
+	}
+}
+
+// handle registers player and reads its answers until connection
+// is closed
+func (g *Game) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	p := &player{conn: conn}
+	p.send("WELCOME Enter your name:")
+
+	lines, errCh := readLines(ctx, conn)
+	for {
+		select {
+		case line := <-lines:
+			if p.name == "" {
+				if err := g.join(p, line); err != nil {
+					p.send("ERROR %v", err)
+					if errors.Is(err, ErrGameStarted) {
+						return
+					}
+					continue
+				}
+				// sent after join unlocks the game, so slow
+				// client doesn't stall other players
+				p.send("JOINED %s", p.name)
+
+				select {
+				case g.joined <- p:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			select {
+			case g.answers <- answer{player: p, text: line, at: time.Now()}:
+			case <-ctx.Done():
+				return
+			}
+		case err := <-errCh:
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("read from %v: %v", conn.RemoteAddr(), err)
+			}
+			g.leave(ctx, p)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// join names player and adds it to the game
+func (g *Game) join(p *player, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("name must be a single word up to %d characters", maxNameLength)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.started {
+		return ErrGameStarted
+	}
+	for _, other := range g.players {
+		if other.name == name && !other.gone {
+			return fmt.Errorf("name %q is taken", name)
+		}
+	}
+
+	p.name = name
+	g.players = append(g.players, p)
+
+	return nil
+}
+
+// leave marks player as disconnected
+func (g *Game) leave(ctx context.Context, p *player) {
+	if p.name == "" {
+		return
+	}
+
+	g.mu.Lock()
+	p.gone = true
+	g.mu.Unlock()
+
+	select {
+	case g.left <- p:
+	case <-ctx.Done():
+	}
+}
+
+// active returns players which are still connected
+func (g *Game) active() []*player {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var active []*player
+	for _, p := range g.players {
+		if !p.gone {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// playing returns connected players which sessions aren't
+// finished
+func (g *Game) playing() []*player {
+	var playing []*player
+	for _, p := range g.active() {
+		if p.session == nil {
+			continue
+		}
+		if _, finished := p.session.Finished(); !finished {
+			playing = append(playing, p)
+		}
+	}
+	return playing
+}
+
+// Leaderboard returns standings of all players ordered by
+// rankStandings
+func (g *Game) Leaderboard() []Standing {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	standings := make([]Standing, 0, len(g.players))
+	for _, p := range g.players {
+		var results []Result
+		if p.session != nil {
+			results = p.session.Results()
+		}
+		s := Standing{Name: p.name, Score: Score{Total: len(results)}}
+		for _, res := range results {
+			s.Time += res.Duration
+			s.Score.add(res, g.Quiz.HintPenalty)
+		}
+		standings = append(standings, s)
+	}
+
+	rankStandings(standings)
+	return standings
+}
+
+// rankStandings orders standings by the score players are shown,
+// the most points first, then the most correct answers, less time
+// breaks ties
+func rankStandings(standings []Standing) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		switch {
+		case a.Score.Points != b.Score.Points:
+			return a.Score.Points > b.Score.Points
+		case a.Score.Correct != b.Score.Correct:
+			return a.Score.Correct > b.Score.Correct
+		default:
+			return a.Time < b.Time
+		}
+	})
+}
+
+// sendLeaderboard sends leaderboard to all players
+func (g *Game) sendLeaderboard() {
+	standings := g.Leaderboard()
+
+	g.broadcast("LEADERBOARD")
+	for i, s := range standings {
+		g.broadcast("RANK %d %s %v %v", i+1, s.Name, s.Score.Points, s.Time.Round(time.Millisecond))
+	}
+	g.broadcast("END")
+}
+
+// printLeaderboard writes leaderboard to game log
+func (g *Game) printLeaderboard() {
+	fmt.Fprintln(g.Out, "Leaderboard:")
+	for i, s := range g.Leaderboard() {
+		fmt.Fprintf(g.Out, "%d. %s: %v out of %d (%v)\n",
+			i+1, s.Name, s.Score.Points, s.Score.Total, s.Time.Round(time.Millisecond))
+	}
+}
+
+// broadcast sends message to all connected players
+func (g *Game) broadcast(format string, args ...interface{}) {
+	for _, p := range g.active() {
+		p.send(format, args...)
+	}
+}
+
+// send writes single line message to player, errors are ignored
+// as disconnected player is removed by its reader
+func (p *player) send(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	fmt.Fprintf(p.conn, format+"\n", args...)
+}
+
+// runServe runs multiplayer game on TCP address
+func (app *appEnv) runServe(ctx context.Context, q Quiz) error {
+	l, err := net.Listen("tcp", app.addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Starting the server on %s", l.Addr())
+	g := NewGame(q, app.players, app.term.Out)
+	if _, err := g.Serve(ctx, l); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
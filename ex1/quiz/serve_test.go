@@ -0,0 +1,193 @@
+package quiz
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client is a test player of the game
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr, name string) *client {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+	c.expect("WELCOME")
+	c.send(name)
+	c.expect("JOINED " + name)
+	return c
+}
+
+func (c *client) send(line string) {
+	if _, err := io.WriteString(c.conn, line+"\n"); err != nil {
+		c.t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// expect skips lines until the one with prefix and returns it
+func (c *client) expect(prefix string) string {
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("waiting for %q: %v", prefix, err)
+		}
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(line)
+		}
+	}
+}
+
+func TestGame(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+		},
+		Policy: DefaultPolicy,
+	}
+	g := NewGame(quiz, 2, io.Discard)
+
+	reasonCh := make(chan Reason, 1)
+	go func() {
+		reason, err := g.Serve(context.Background(), l)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		reasonCh <- reason
+	}()
+
+	alice := dial(t, l.Addr().String(), "alice")
+	bob := dial(t, l.Addr().String(), "bob")
+
+	for _, c := range []*client{alice, bob} {
+		if got := c.expect("QUESTION"); got != "QUESTION 1/2 0s: 5+5" {
+			t.Errorf("got %q, want the first question", got)
+		}
+	}
+	alice.send("10")
+	alice.expect("ACCEPTED")
+	bob.send("11")
+	if got := bob.expect("RESULT"); got != "RESULT wrong 10" {
+		t.Errorf("got %q, want wrong result", got)
+	}
+
+	for _, c := range []*client{alice, bob} {
+		c.expect("QUESTION 2/2")
+	}
+	bob.send("2")
+	bob.expect("ACCEPTED")
+	alice.send("2")
+
+	alice.expect("FINISHED completed")
+	alice.expect("LEADERBOARD")
+	if got := alice.expect("RANK"); !strings.HasPrefix(got, "RANK 1 alice 2 ") {
+		t.Errorf("got %q, want alice to be the first", got)
+	}
+	if got := alice.expect("RANK"); !strings.HasPrefix(got, "RANK 2 bob 1 ") {
+		t.Errorf("got %q, want bob to be the second", got)
+	}
+
+	select {
+	case reason := <-reasonCh:
+		if reason != ReasonCompleted {
+			t.Errorf("got reason %v, want completed", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("game wasn't finished")
+	}
+}
+
+func TestGameQuestionLimit(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	quiz := Quiz{
+		Problems:      []Problem{{Question: "5+5", Answer: "10"}},
+		Policy:        DefaultPolicy,
+		QuestionLimit: 100 * time.Millisecond,
+	}
+	g := NewGame(quiz, 1, io.Discard)
+	go g.Serve(context.Background(), l)
+
+	alice := dial(t, l.Addr().String(), "alice")
+	alice.expect("QUESTION")
+	if got := alice.expect("RESULT"); got != "RESULT timed_out 10" {
+		t.Errorf("got %q, want timed out result", got)
+	}
+	alice.expect("FINISHED completed")
+}
+
+func TestGameLimit(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+		},
+		Policy: DefaultPolicy,
+		Limit:  300 * time.Millisecond,
+	}
+	g := NewGame(quiz, 2, io.Discard)
+	go g.Serve(context.Background(), l)
+
+	alice := dial(t, l.Addr().String(), "alice")
+	bob := dial(t, l.Addr().String(), "bob")
+
+	alice.expect("QUESTION 1/2")
+	alice.send("10")
+	alice.expect("ACCEPTED")
+
+	// bob runs out of time while alice doesn't, as session of
+	// alice is paused while waiting for bob
+	if got := bob.expect("RESULT"); got != "RESULT timed_out 10" {
+		t.Errorf("got %q, want timed out result", got)
+	}
+	alice.expect("QUESTION 2/2")
+	alice.send("2")
+	alice.expect("FINISHED completed")
+	if got := alice.expect("RANK"); !strings.HasPrefix(got, "RANK 1 alice 2 ") {
+		t.Errorf("got %q, want alice to be the first", got)
+	}
+}
+
+func TestRankStandings(t *testing.T) {
+	standings := []Standing{
+		{Name: "slow", Score: Score{Correct: 2, Points: 2}, Time: 3 * time.Second},
+		{Name: "hinted", Score: Score{Correct: 3, Points: 1.5}, Time: time.Second},
+		{Name: "fast", Score: Score{Correct: 2, Points: 2}, Time: 2 * time.Second},
+		{Name: "wrong", Score: Score{Correct: 1, Points: 1.5}, Time: time.Second},
+	}
+	rankStandings(standings)
+
+	var got []string
+	for _, s := range standings {
+		got = append(got, s.Name)
+	}
+	if want := "fast slow hinted wrong"; strings.Join(got, " ") != want {
+		t.Errorf("got order %v, want %s", got, want)
+	}
+}
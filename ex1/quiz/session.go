@@ -0,0 +1,717 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFinished is returned when answer is submitted to the
+	// finished session
+	ErrFinished = errors.New("quiz is finished")
+	// ErrTimedOut is returned when answer is submitted after the
+	// problem's time limit
+	ErrTimedOut = errors.New("time is up for this problem")
+	// ErrPaused is returned when answer is submitted to the paused
+	// session
+	ErrPaused = errors.New("quiz is paused")
+	// ErrNoHint is returned when hint is requested for the problem
+	// which has no hint
+	ErrNoHint = errors.New("problem has no hint")
+)
+
+// Quiz is a set of problems and rules of asking and grading them
+type Quiz struct {
+	Problems []Problem
+	// Policy defines how text answers are compared
+	Policy Policy
+	// Limit is the time limit for the whole quiz, 0 means no limit
+	Limit time.Duration
+	// QuestionLimit is the default time limit for a single problem,
+	// it is overridden by Problem.TimeLimit, 0 means no limit
+	QuestionLimit time.Duration
+	// Shuffle asks problems in random order
+	Shuffle bool
+	// ShuffleOptions shows multiple choice options in random order
+	ShuffleOptions bool
+	// Count is the number of randomly picked problems asked in a
+	// session, 0 means all problems
+	Count int
+	// Seed makes random order reproducible, if it is 0 every
+	// session gets its own seed
+	Seed int64
+	// HintPenalty is the number of points subtracted from the
+	// score for correct answer given after the hint
+	HintPenalty float64
+	// Generated reports whether problems were generated from Seed,
+	// it is shown to reproduce them
+	Generated bool
+	// OnResult is called when problem is answered or timed out, it
+	// may be nil. It is called while session is locked, so it must
+	// not call Session methods.
+	OnResult func(r Result)
+}
+
+// LoadFile reads problems from file in given format, if format
+// is empty it is detected by file extension
+func LoadFile(fileName, format string) ([]Problem, error) {
+	src, err := sourceFor(format, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	problems, err := src.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", fileName, err)
+	}
+
+	return problems, nil
+}
+
+// questionLimit returns time limit for a single problem, problem's
+// own limit overrides the default one, 0 means no limit
+func (q Quiz) questionLimit(p Problem) time.Duration {
+	if p.TimeLimit > 0 {
+		return time.Second * time.Duration(p.TimeLimit)
+	}
+	return q.QuestionLimit
+}
+
+// Result is the outcome of a single problem
+type Result struct {
+	Problem  Problem `json:"problem"`
+	Answer   string  `json:"answer"`
+	Correct  bool    `json:"correct"`
+	TimedOut bool    `json:"timed_out"`
+	Hinted   bool    `json:"hinted,omitempty"`
+	// Duration is the time between asking the problem and
+	// getting the answer
+	Duration time.Duration `json:"duration"`
+}
+
+// Progress is the state of unfinished session, the session is
+// continued from it by Quiz.Resume
+type Progress struct {
+	Seed      int64     `json:"seed"`
+	StartedAt time.Time `json:"started_at"`
+	// Index is the number of the current problem starting from 0
+	Index   int      `json:"index"`
+	Results []Result `json:"results"`
+	// Elapsed is the quiz time used, pauses are not counted
+	Elapsed time.Duration `json:"elapsed"`
+	// QuestionElapsed is the time spent on the current problem
+	QuestionElapsed time.Duration `json:"question_elapsed"`
+	// Hinted is set if hint of the current problem was shown
+	Hinted bool `json:"hinted,omitempty"`
+}
+
+// Score summarizes session results
+type Score struct {
+	Correct  int `json:"correct"`
+	TimedOut int `json:"timed_out"`
+	Total    int `json:"total"`
+	Hints    int `json:"hints,omitempty"`
+	// Points is the number of correct answers minus hint penalties
+	Points float64 `json:"points"`
+}
+
+// add counts result in the score, hint penalty is subtracted for
+// correct answers given after the hint
+func (sc *Score) add(res Result, hintPenalty float64) {
+	if res.Hinted {
+		sc.Hints++
+	}
+	if res.TimedOut {
+		sc.TimedOut++
+	}
+	if !res.Correct {
+		return
+	}
+
+	sc.Correct++
+	sc.Points++
+	if res.Hinted {
+		sc.Points -= hintPenalty
+	}
+}
+
+// Reason describes why session has finished
+type Reason int
+
+const (
+	// ReasonCompleted means all problems were asked
+	ReasonCompleted Reason = iota + 1
+	// ReasonTimeUp means the quiz time limit was exceeded
+	ReasonTimeUp
+	// ReasonCanceled means session context was canceled
+	ReasonCanceled
+	// ReasonStopped means session was stopped by frontend
+	ReasonStopped
+)
+
+// String returns the name of the reason used in reports
+func (r Reason) String() string {
+	switch r {
+	case ReasonCompleted:
+		return "completed"
+	case ReasonTimeUp:
+		return "time_up"
+	case ReasonCanceled:
+		return "canceled"
+	case ReasonStopped:
+		return "stopped"
+	default:
+		return "unfinished"
+	}
+}
+
+// EventKind is a type of session event
+type EventKind int
+
+const (
+	// EventQuestion is sent when new problem is asked
+	EventQuestion EventKind = iota + 1
+	// EventAnswered is sent when answer is submitted
+	EventAnswered
+	// EventTimeout is sent when problem's time limit is exceeded
+	EventTimeout
+	// EventFinished is the last event sent by session
+	EventFinished
+)
+
+// Event notifies frontend about session state changes
+type Event struct {
+	Kind EventKind
+	// Index is the number of the problem starting from 0
+	Index   int
+	Problem Problem
+	// Deadline is the time until the asked problem should be
+	// answered, it is zero if there is no limit
+	Deadline time.Time
+	// Result is set for EventAnswered and EventTimeout
+	Result Result
+	// Reason is set for EventFinished
+	Reason Reason
+}
+
+// Session is a single run of the quiz, it is safe for concurrent use
+type Session struct {
+	quiz     Quiz
+	problems []Problem
+	seed     int64
+	started  time.Time
+
+	mu       sync.Mutex
+	index    int
+	results  []Result
+	asked    time.Time
+	deadline time.Time
+	// questionDeadline is zero if problem has no own limit
+	questionDeadline time.Time
+	// hinted is set when hint of the current problem is shown
+	hinted bool
+	reason Reason
+	// elapsed is the quiz time used until resumed, resumed is
+	// zero while session is paused or finished
+	elapsed time.Duration
+	resumed time.Time
+	// stopped is the time session was paused or finished
+	stopped time.Time
+	paused  bool
+	// finished is the time session was finished
+	finished time.Time
+
+	events  chan Event
+	done    chan struct{}
+	changed chan struct{}
+}
+
+// Start begins new session, it is finished when all problems are
+// answered, time is up or ctx is done
+func (q Quiz) Start(ctx context.Context) *Session {
+	s := q.newSession(q.newSeed())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.started = now
+	s.runLocked(ctx, now, 0, 0)
+
+	return s
+}
+
+// Resume continues session from saved progress with the same
+// problems, results and time left
+func (q Quiz) Resume(ctx context.Context, p Progress) (*Session, error) {
+	s := q.newSession(p.Seed)
+	if p.Index != len(p.Results) || p.Index >= len(s.problems) {
+		return nil, fmt.Errorf("progress at problem #%d doesn't match the quiz", p.Index+1)
+	}
+	for i, res := range p.Results {
+		if res.Problem.Question != s.problems[i].Question {
+			return nil, fmt.Errorf("problem #%d doesn't match the quiz", i+1)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results = append(s.results, p.Results...)
+	s.index = p.Index
+	s.started = p.StartedAt
+	s.runLocked(ctx, time.Now(), p.Elapsed, p.QuestionElapsed)
+	s.hinted = p.Hinted
+
+	return s, nil
+}
+
+// newSession creates session with problems arranged by seed
+func (q Quiz) newSession(seed int64) *Session {
+	problems := q.arrange(seed)
+	return &Session{
+		quiz:     q,
+		problems: problems,
+		seed:     seed,
+		results:  make([]Result, 0, len(problems)),
+		// every problem emits at most two events and the session
+		// emits one more when finished, so emitting never blocks
+		events:  make(chan Event, 2*len(problems)+1),
+		done:    make(chan struct{}),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// runLocked asks the current problem, elapsed times are already
+// used of the quiz and the problem limits
+func (s *Session) runLocked(ctx context.Context, now time.Time, elapsed, questionElapsed time.Duration) {
+	s.elapsed = elapsed
+	s.resumed = now
+	if s.quiz.Limit > 0 {
+		s.deadline = now.Add(s.quiz.Limit - elapsed)
+	}
+	s.askLocked(now.Add(-questionElapsed))
+
+	if s.reason == 0 {
+		go s.watch(ctx)
+	}
+}
+
+// Quiz returns quiz the session was started from
+func (s *Session) Quiz() Quiz {
+	return s.quiz
+}
+
+// Seed returns seed used to arrange session problems, the quiz
+// started with this seed asks the same problems in the same order
+func (s *Session) Seed() int64 {
+	return s.seed
+}
+
+// StartedAt returns the time session was started
+func (s *Session) StartedAt() time.Time {
+	return s.started
+}
+
+// Elapsed returns the quiz time used, time while session was
+// paused or interrupted isn't counted
+func (s *Session) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.elapsed
+	if !s.resumed.IsZero() {
+		d += time.Since(s.resumed)
+	}
+	return d
+}
+
+// Done returns channel which is closed when session is finished
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Events returns channel of session events, it is closed after
+// EventFinished
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
+// Next returns the problem waiting for the answer and its index,
+// ok is false if session is finished
+func (s *Session) Next() (index int, p Problem, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked(time.Now())
+	if s.reason != 0 {
+		return s.index, Problem{}, false
+	}
+
+	return s.index, s.problems[s.index], true
+}
+
+// Deadline returns the time until the current problem should be
+// answered, it is zero if there is no limit
+func (s *Session) Deadline() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.currentDeadlineLocked()
+}
+
+// Submit grades the answer to the current problem and asks
+// the next one
+func (s *Session) Submit(answer string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	index := s.index
+	s.expireLocked(now)
+	if s.reason != 0 {
+		return Result{}, ErrFinished
+	}
+	if index != s.index {
+		return Result{}, ErrTimedOut
+	}
+	if s.paused {
+		return Result{}, ErrPaused
+	}
+
+	p := s.problems[s.index]
+	res := Result{
+		Problem:  p,
+		Answer:   answer,
+		Correct:  p.Check(answer, s.quiz.Policy),
+		Hinted:   s.hinted,
+		Duration: now.Sub(s.asked),
+	}
+	s.addResultLocked(EventAnswered, res)
+
+	s.index++
+	s.askLocked(now)
+
+	return res, nil
+}
+
+// Hint returns hint of the current problem, correct answer to the
+// problem is penalized after that
+func (s *Session) Hint() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked(time.Now())
+	if s.reason != 0 {
+		return "", ErrFinished
+	}
+	if s.paused {
+		return "", ErrPaused
+	}
+
+	p := s.problems[s.index]
+	if p.Hint == "" {
+		return "", ErrNoHint
+	}
+	s.hinted = true
+
+	return p.Hint, nil
+}
+
+// Pause suspends the session timers, it reports false if session
+// is finished or already paused
+func (s *Session) Pause() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expireLocked(now)
+	if s.reason != 0 || s.paused {
+		return false
+	}
+
+	s.paused = true
+	s.freezeLocked(now)
+	s.notify()
+
+	return true
+}
+
+// Resume continues the paused session, deadlines are moved by the
+// time session was paused
+func (s *Session) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reason != 0 || !s.paused {
+		return
+	}
+
+	now := time.Now()
+	pause := now.Sub(s.stopped)
+	s.asked = s.asked.Add(pause)
+	if !s.deadline.IsZero() {
+		s.deadline = s.deadline.Add(pause)
+	}
+	if !s.questionDeadline.IsZero() {
+		s.questionDeadline = s.questionDeadline.Add(pause)
+	}
+
+	s.paused = false
+	s.resumed = now
+	s.notify()
+}
+
+// Paused reports whether session is paused
+func (s *Session) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.paused
+}
+
+// Progress returns the state to resume the session from, the
+// current problem is asked again with the time already spent on it
+func (s *Session) Progress() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.resumed.IsZero() {
+		now = s.stopped
+	}
+
+	p := Progress{
+		Seed:      s.seed,
+		StartedAt: s.started,
+		Index:     s.index,
+		Results:   make([]Result, len(s.results)),
+		Elapsed:   s.elapsed,
+	}
+	copy(p.Results, s.results)
+	if !s.resumed.IsZero() {
+		p.Elapsed += now.Sub(s.resumed)
+	}
+	if s.index < len(s.problems) {
+		p.QuestionElapsed = now.Sub(s.asked)
+		p.Hinted = s.hinted
+	}
+
+	return p
+}
+
+// Stop finishes the session before all problems are answered
+func (s *Session) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.finishLocked(ReasonStopped)
+}
+
+// Finished reports whether session is finished and why
+func (s *Session) Finished() (Reason, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked(time.Now())
+	return s.reason, s.reason != 0
+}
+
+// FinishedAt returns the time session was finished, it is zero
+// while session is running
+func (s *Session) FinishedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked(time.Now())
+	return s.finished
+}
+
+// Results returns results of answered and timed out problems
+func (s *Session) Results() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]Result, len(s.results))
+	copy(results, s.results)
+	return results
+}
+
+// Problems returns all problems of the session in the order
+// they are asked
+func (s *Session) Problems() []Problem {
+	problems := make([]Problem, len(s.problems))
+	copy(problems, s.problems)
+	return problems
+}
+
+// Score returns the number of correct answers
+func (s *Session) Score() Score {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	score := Score{Total: len(s.problems)}
+	for _, res := range s.results {
+		score.add(res, s.quiz.HintPenalty)
+	}
+
+	return score
+}
+
+// watch expires deadlines and finishes session when ctx is done
+func (s *Session) watch(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		if s.reason != 0 {
+			s.mu.Unlock()
+			return
+		}
+		deadline := s.currentDeadlineLocked()
+		s.mu.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			timer = time.NewTimer(time.Until(deadline))
+			expired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.finishLocked(ReasonCanceled)
+			s.mu.Unlock()
+		case <-expired:
+			s.mu.Lock()
+			s.expireLocked(time.Now())
+			s.mu.Unlock()
+		case <-s.changed:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// askLocked starts the current problem or finishes the session
+// if there are no problems left
+func (s *Session) askLocked(now time.Time) {
+	if s.index >= len(s.problems) {
+		s.finishLocked(ReasonCompleted)
+		return
+	}
+
+	p := s.problems[s.index]
+	s.asked = now
+	s.hinted = false
+	s.questionDeadline = time.Time{}
+	if limit := s.quiz.questionLimit(p); limit > 0 {
+		s.questionDeadline = now.Add(limit)
+	}
+
+	s.emit(Event{Kind: EventQuestion, Index: s.index, Problem: p, Deadline: s.currentDeadlineLocked()})
+	s.notify()
+}
+
+// expireLocked times out the current problem or finishes the
+// session if deadlines have passed by now
+func (s *Session) expireLocked(now time.Time) {
+	if s.reason != 0 || s.paused {
+		return
+	}
+
+	if !s.deadline.IsZero() && !now.Before(s.deadline) {
+		s.finishLocked(ReasonTimeUp)
+		return
+	}
+
+	if !s.questionDeadline.IsZero() && !now.Before(s.questionDeadline) {
+		p := s.problems[s.index]
+		res := Result{
+			Problem:  p,
+			TimedOut: true,
+			Hinted:   s.hinted,
+			Duration: s.questionDeadline.Sub(s.asked),
+		}
+		s.addResultLocked(EventTimeout, res)
+
+		s.index++
+		s.askLocked(s.questionDeadline)
+	}
+}
+
+// addResultLocked saves result of the current problem and
+// notifies about it
+func (s *Session) addResultLocked(kind EventKind, res Result) {
+	s.results = append(s.results, res)
+	s.emit(Event{Kind: kind, Index: s.index, Problem: res.Problem, Result: res})
+
+	if s.quiz.OnResult != nil {
+		s.quiz.OnResult(res)
+	}
+}
+
+// finishLocked finishes the session, it is no-op if session is
+// already finished
+func (s *Session) finishLocked(reason Reason) {
+	if s.reason != 0 {
+		return
+	}
+
+	s.reason = reason
+	s.finished = time.Now()
+	if !s.paused {
+		s.freezeLocked(time.Now())
+	}
+	s.emit(Event{Kind: EventFinished, Index: s.index, Reason: reason})
+	close(s.events)
+	close(s.done)
+	s.notify()
+}
+
+// freezeLocked stops counting of the quiz time used
+func (s *Session) freezeLocked(now time.Time) {
+	s.elapsed += now.Sub(s.resumed)
+	s.resumed = time.Time{}
+	s.stopped = now
+}
+
+// currentDeadlineLocked returns the nearest of quiz and problem
+// deadlines, it is zero while session is paused
+func (s *Session) currentDeadlineLocked() time.Time {
+	if s.paused {
+		return time.Time{}
+	}
+	if s.questionDeadline.IsZero() {
+		return s.deadline
+	}
+	if s.deadline.IsZero() || s.questionDeadline.Before(s.deadline) {
+		return s.questionDeadline
+	}
+	return s.deadline
+}
+
+// emit sends event without blocking, channel capacity is enough
+// to hold all events of the session
+func (s *Session) emit(e Event) {
+	s.events <- e
+}
+
+// notify wakes up watch goroutine to recalculate deadlines
+func (s *Session) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
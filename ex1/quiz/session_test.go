@@ -0,0 +1,242 @@
+package quiz
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionSubmit(t *testing.T) {
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+		},
+		Policy: DefaultPolicy,
+	}
+	s := quiz.Start(context.Background())
+
+	index, p, ok := s.Next()
+	if !ok || index != 0 || p.Question != "5+5" {
+		t.Fatalf("got problem #%d %+v (%v), want the first one", index, p, ok)
+	}
+
+	res, err := s.Submit("10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Correct {
+		t.Errorf("got incorrect result for the right answer")
+	}
+
+	if _, err = s.Submit("3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, _, ok = s.Next(); ok {
+		t.Error("got next problem after all problems were answered")
+	}
+	if reason, ok := s.Finished(); !ok || reason != ReasonCompleted {
+		t.Errorf("got reason %v (%v), want ReasonCompleted", reason, ok)
+	}
+	if _, err = s.Submit("2"); err != ErrFinished {
+		t.Errorf("got error %v, want ErrFinished", err)
+	}
+
+	want := Score{Correct: 1, Total: 2, Points: 1}
+	if got := s.Score(); got != want {
+		t.Errorf("got score %+v, want %+v", got, want)
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+		},
+		QuestionLimit: 20 * time.Millisecond,
+	}
+	s := quiz.Start(context.Background())
+
+	if _, err := s.Submit("10"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var kinds []EventKind
+	for e := range s.Events() {
+		kinds = append(kinds, e.Kind)
+	}
+
+	want := []EventKind{EventQuestion, EventAnswered, EventQuestion, EventTimeout, EventFinished}
+	if len(kinds) != len(want) {
+		t.Fatalf("got events %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("got events %v, want %v", kinds, want)
+		}
+	}
+
+	want2 := Score{Correct: 1, TimedOut: 1, Total: 2, Points: 1}
+	if got := s.Score(); got != want2 {
+		t.Errorf("got score %+v, want %+v", got, want2)
+	}
+}
+
+func TestSessionFinish(t *testing.T) {
+	problems := []Problem{{Question: "5+5", Answer: "10"}}
+
+	t.Run("time up", func(t *testing.T) {
+		s := Quiz{Problems: problems, Limit: 20 * time.Millisecond}.Start(context.Background())
+		waitFinished(t, s, ReasonTimeUp)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := Quiz{Problems: problems}.Start(ctx)
+		cancel()
+		waitFinished(t, s, ReasonCanceled)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		s := Quiz{Problems: problems}.Start(context.Background())
+		s.Stop()
+		waitFinished(t, s, ReasonStopped)
+	})
+
+	t.Run("no problems", func(t *testing.T) {
+		s := Quiz{}.Start(context.Background())
+		waitFinished(t, s, ReasonCompleted)
+	})
+}
+
+// waitFinished waits for EventFinished and checks its reason
+func waitFinished(t *testing.T, s *Session, want Reason) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-s.Events():
+			if e.Kind != EventFinished {
+				continue
+			}
+			if e.Reason != want {
+				t.Errorf("got reason %v, want %v", e.Reason, want)
+			}
+			return
+		case <-timeout:
+			t.Fatal("session is not finished")
+		}
+	}
+}
+
+func TestSessionPause(t *testing.T) {
+	quiz := Quiz{
+		Problems: []Problem{{Question: "5+5", Answer: "10"}},
+		Limit:    50 * time.Millisecond,
+	}
+	s := quiz.Start(context.Background())
+
+	if !s.Pause() {
+		t.Fatal("session wasn't paused")
+	}
+	if s.Pause() {
+		t.Error("paused session was paused again")
+	}
+	if _, err := s.Submit("10"); err != ErrPaused {
+		t.Errorf("got error %v, want ErrPaused", err)
+	}
+
+	// the time limit passes while session is paused
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := s.Finished(); ok {
+		t.Fatal("paused session is finished")
+	}
+
+	s.Resume()
+	if res, err := s.Submit("10"); err != nil || !res.Correct {
+		t.Errorf("got result %+v and error %v, want correct answer", res, err)
+	}
+	if res := s.Results()[0]; res.Duration >= 50*time.Millisecond {
+		t.Errorf("got duration %v including pause", res.Duration)
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10"},
+			{Question: "1+1", Answer: "2"},
+			{Question: "2+2", Answer: "4"},
+		},
+		Policy:  DefaultPolicy,
+		Limit:   time.Minute,
+		Shuffle: true,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := quiz.Start(ctx)
+	_, first, _ := s.Next()
+	if _, err := s.Submit(first.Answer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	waitFinished(t, s, ReasonCanceled)
+
+	p := s.Progress()
+	if p.Index != 1 || len(p.Results) != 1 || p.Elapsed <= 0 {
+		t.Fatalf("got progress %+v, want the second problem", p)
+	}
+
+	resumed, err := quiz.Resume(context.Background(), p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index, next, ok := resumed.Next()
+	if !ok || index != 1 || next.Question != s.Problems()[1].Question {
+		t.Errorf("got problem #%d %+v, want the second one", index, next)
+	}
+	if got := resumed.Score(); got.Correct != 1 {
+		t.Errorf("got score %+v, want one correct answer", got)
+	}
+	if left := time.Until(resumed.Deadline()); left > time.Minute-p.Elapsed {
+		t.Errorf("got %v left, want at most %v", left, time.Minute-p.Elapsed)
+	}
+
+	p.Results[0].Problem.Question = "3+3"
+	if _, err := quiz.Resume(context.Background(), p); err == nil {
+		t.Error("expected error for progress of another quiz")
+	}
+}
+
+func TestSessionHint(t *testing.T) {
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "5+5", Answer: "10", Hint: "two fives"},
+			{Question: "1+1", Answer: "2"},
+		},
+		Policy:      DefaultPolicy,
+		HintPenalty: 0.5,
+	}
+	s := quiz.Start(context.Background())
+
+	hint, err := s.Hint()
+	if err != nil || hint != "two fives" {
+		t.Errorf("got hint %q and error %v, want two fives", hint, err)
+	}
+	if res, _ := s.Submit("10"); !res.Correct || !res.Hinted {
+		t.Errorf("got result %+v, want correct answer after the hint", res)
+	}
+
+	if _, err := s.Hint(); err != ErrNoHint {
+		t.Errorf("got error %v, want ErrNoHint", err)
+	}
+	s.Submit("2")
+
+	want := Score{Correct: 2, Total: 2, Hints: 1, Points: 1.5}
+	if got := s.Score(); got != want {
+		t.Errorf("got score %+v, want %+v", got, want)
+	}
+}
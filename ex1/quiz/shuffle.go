@@ -0,0 +1,84 @@
+package quiz
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// randomized reports whether quiz asks problems in random order,
+// their random subset, shuffles options or generated problems
+func (q Quiz) randomized() bool {
+	return q.Generated || q.Shuffle || q.ShuffleOptions || (q.Count > 0 && q.Count < len(q.Problems))
+}
+
+// size returns the number of problems asked in a single session
+func (q Quiz) size() int {
+	if q.Count > 0 && q.Count < len(q.Problems) {
+		return q.Count
+	}
+	return len(q.Problems)
+}
+
+// newSeed returns quiz seed or generates new one if it isn't set
+func (q Quiz) newSeed() int64 {
+	if q.Seed != 0 {
+		return q.Seed
+	}
+	return time.Now().UnixNano()
+}
+
+// arrange returns problems of a single session, the same seed
+// always gives the same problems in the same order
+func (q Quiz) arrange(seed int64) []Problem {
+	r := rand.New(rand.NewSource(seed))
+
+	idx := make([]int, len(q.Problems))
+	for i := range idx {
+		idx[i] = i
+	}
+	if q.Shuffle || q.size() < len(q.Problems) {
+		r.Shuffle(len(idx), func(i, j int) {
+			idx[i], idx[j] = idx[j], idx[i]
+		})
+	}
+
+	idx = idx[:q.size()]
+	if !q.Shuffle {
+		// keep the file order of randomly picked problems
+		sort.Ints(idx)
+	}
+
+	problems := make([]Problem, len(idx))
+	for i, j := range idx {
+		problems[i] = q.Problems[j]
+		if q.ShuffleOptions {
+			problems[i] = problems[i].shuffleOptions(r)
+		}
+	}
+
+	return problems
+}
+
+// shuffleOptions returns copy of multiple choice problem with
+// options in random order, answer is changed to the new letter
+// of the correct option
+func (p Problem) shuffleOptions(r *rand.Rand) Problem {
+	if p.kind() != KindChoice {
+		return p
+	}
+
+	correct := p.correctOption()
+	perm := r.Perm(len(p.Options))
+
+	options := make([]string, len(p.Options))
+	for i, j := range perm {
+		options[i] = p.Options[j]
+		if j == correct {
+			p.Answer = string(optionLetter(i))
+		}
+	}
+	p.Options = options
+
+	return p
+}
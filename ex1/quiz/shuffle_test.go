@@ -0,0 +1,64 @@
+package quiz
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestQuizArrange(t *testing.T) {
+	problems := make([]Problem, 20)
+	for i := range problems {
+		problems[i] = Problem{Question: string(rune('a' + i)), Answer: "x"}
+	}
+
+	t.Run("no randomization", func(t *testing.T) {
+		q := Quiz{Problems: problems}
+		if got := q.arrange(1); !reflect.DeepEqual(got, problems) {
+			t.Errorf("got %v, want problems in file order", got)
+		}
+	})
+
+	t.Run("same seed", func(t *testing.T) {
+		q := Quiz{Problems: problems, Shuffle: true}
+		first, second := q.arrange(42), q.arrange(42)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("got different order for the same seed: %v and %v", first, second)
+		}
+		if reflect.DeepEqual(first, problems) {
+			t.Error("problems are not shuffled")
+		}
+	})
+
+	t.Run("subset in file order", func(t *testing.T) {
+		q := Quiz{Problems: problems, Count: 5}
+		got := q.arrange(7)
+		if len(got) != 5 {
+			t.Fatalf("got %d problems, want 5", len(got))
+		}
+		if !sort.SliceIsSorted(got, func(i, j int) bool { return got[i].Question < got[j].Question }) {
+			t.Errorf("got %v, want subset in file order", got)
+		}
+	})
+}
+
+func TestProblemShuffleOptions(t *testing.T) {
+	p := Problem{
+		Kind:     KindChoice,
+		Question: "Capital of France?",
+		Options:  []string{"London", "Paris", "Berlin", "Madrid"},
+		Answer:   "b",
+	}
+
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 10; i++ {
+		got := p.shuffleOptions(r)
+		if !got.Check("Paris", DefaultPolicy) || got.ExpectedAnswer()[3:] != "Paris" {
+			t.Fatalf("correct option is lost after shuffle: %+v", got)
+		}
+	}
+	if p.Options[1] != "Paris" {
+		t.Error("original options are changed")
+	}
+}
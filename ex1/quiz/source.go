@@ -0,0 +1,271 @@
+package quiz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProblemSource parses problems from some file format
+type ProblemSource interface {
+	Parse(r io.Reader) ([]Problem, error)
+}
+
+// ParseError describes malformed record in problem file
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// sources maps format name to its ProblemSource
+var sources = map[string]ProblemSource{
+	"csv":   CSVSource{},
+	"json":  JSONSource{},
+	"jsonl": JSONLSource{},
+	"yaml":  YAMLSource{},
+}
+
+// extensions maps file extension to format name
+var extensions = map[string]string{
+	".csv":    "csv",
+	".json":   "json",
+	".jsonl":  "jsonl",
+	".ndjson": "jsonl",
+	".yaml":   "yaml",
+	".yml":    "yaml",
+}
+
+// sourceFor returns ProblemSource by format name, if format is
+// empty it is detected by file extension
+func sourceFor(format, fileName string) (ProblemSource, error) {
+	if format == "" {
+		ext := strings.ToLower(filepath.Ext(fileName))
+		f, ok := extensions[ext]
+		if !ok {
+			return nil, fmt.Errorf("can't detect format of %q, use -format flag", fileName)
+		}
+		format = f
+	}
+
+	src, ok := sources[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	return src, nil
+}
+
+// CSVSource parses CSV in the format of
+// "question,answer[,category[,difficulty[,explanation[,hint]]]]"
+type CSVSource struct{}
+
+// Parse implements ProblemSource
+func (CSVSource) Parse(r io.Reader) ([]Problem, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var problems []Problem
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		if len(record) < 2 || len(record) > 6 {
+			return nil, &ParseError{
+				Line: line,
+				Err:  fmt.Errorf("wrong number of fields: %d instead of 2-6", len(record)),
+			}
+		}
+
+		// pad optional columns so they can be indexed safely
+		record = append(record, make([]string, 6-len(record))...)
+		p := Problem{
+			Question:    record[0],
+			Answer:      record[1],
+			Category:    record[2],
+			Difficulty:  record[3],
+			Explanation: record[4],
+			Hint:        record[5],
+		}
+		if err := p.validate(); err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+
+		problems = append(problems, p)
+	}
+
+	return problems, nil
+}
+
+// JSONSource parses JSON array of problems:
+//
+//	[
+//		{
+//			"question": "5+5",
+//			"answer": "10"
+//		}
+//	]
+type JSONSource struct{}
+
+// Parse implements ProblemSource
+func (JSONSource) Parse(r io.Reader) ([]Problem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, jsonError(data, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, &ParseError{Line: 1, Err: errors.New("expected array of problems")}
+	}
+
+	var problems []Problem
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+
+		var p Problem
+		if err := dec.Decode(&p); err != nil {
+			return nil, jsonError(data, err)
+		}
+		if err := p.validate(); err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+
+		problems = append(problems, p)
+	}
+
+	// closing bracket of the array must be the end of data
+	if _, err := dec.Token(); err != nil {
+		return nil, jsonError(data, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, &ParseError{Line: lineAt(data, dec.InputOffset()), Err: errors.New("unexpected data after array of problems")}
+	}
+
+	return problems, nil
+}
+
+// jsonError converts JSON decoding error to ParseError if
+// error position is known
+func jsonError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &ParseError{Line: lineAt(data, syntaxErr.Offset), Err: err}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ParseError{Line: lineAt(data, typeErr.Offset), Err: err}
+	}
+
+	return err
+}
+
+// lineAt returns line number of the first meaningful byte
+// starting from offset
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+		offset++
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// JSONLSource parses one JSON problem object per line
+type JSONLSource struct{}
+
+// Parse implements ProblemSource
+func (JSONLSource) Parse(r io.Reader) ([]Problem, error) {
+	var problems []Problem
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+
+		var p Problem
+		if err := json.Unmarshal(s.Bytes(), &p); err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		if err := p.validate(); err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+
+		problems = append(problems, p)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return problems, nil
+}
+
+// YAMLSource parses YAML sequence of problems:
+//
+//   - question: 5+5
+//     answer: 10
+type YAMLSource struct{}
+
+// Parse implements ProblemSource
+func (YAMLSource) Parse(r io.Reader) ([]Problem, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.SequenceNode {
+		return nil, &ParseError{Line: root.Line, Err: errors.New("expected sequence of problems")}
+	}
+
+	problems := make([]Problem, 0, len(root.Content))
+	for _, node := range root.Content {
+		var p Problem
+		if err := node.Decode(&p); err != nil {
+			return nil, &ParseError{Line: node.Line, Err: err}
+		}
+		if err := p.validate(); err != nil {
+			return nil, &ParseError{Line: node.Line, Err: err}
+		}
+
+		problems = append(problems, p)
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,162 @@
+package quiz
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProblemSourceParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		source ProblemSource
+		input  string
+		want   []Problem
+	}{
+		{
+			"csv",
+			CSVSource{},
+			"5+5,10\n\"what 2+2, sir?\",4,math,easy,two plus two,even number\n",
+			[]Problem{
+				{Question: "5+5", Answer: "10"},
+				{Question: "what 2+2, sir?", Answer: "4", Category: "math", Difficulty: "easy", Explanation: "two plus two", Hint: "even number"},
+			},
+		},
+		{
+			"json",
+			JSONSource{},
+			`[{"question": "5+5", "answer": "10", "category": "math"}]`,
+			[]Problem{
+				{Question: "5+5", Answer: "10", Category: "math"},
+			},
+		},
+		{
+			"jsonl",
+			JSONLSource{},
+			"{\"question\": \"5+5\", \"answer\": \"10\"}\n\n{\"question\": \"1+1\", \"answer\": \"2\"}\n",
+			[]Problem{
+				{Question: "5+5", Answer: "10"},
+				{Question: "1+1", Answer: "2"},
+			},
+		},
+		{
+			"yaml",
+			YAMLSource{},
+			"- question: 5+5\n  answer: 10\n  difficulty: easy\n  limit: 5\n",
+			[]Problem{
+				{Question: "5+5", Answer: "10", Difficulty: "easy", TimeLimit: 5},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.source.Parse(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProblemSourceParseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source ProblemSource
+		input  string
+		line   int
+	}{
+		{
+			"csv single column",
+			CSVSource{},
+			"5+5,10\n1+1\n",
+			2,
+		},
+		{
+			"csv empty answer",
+			CSVSource{},
+			"5+5,10\n1+1,2\n3+3, \n",
+			3,
+		},
+		{
+			"json missing question",
+			JSONSource{},
+			"[\n  {\"question\": \"5+5\", \"answer\": \"10\"},\n  {\"answer\": \"2\"}\n]",
+			3,
+		},
+		{
+			"json wrong type",
+			JSONSource{},
+			"[\n  {\"question\": \"5+5\",\n   \"answer\": 10}\n]",
+			3,
+		},
+		{
+			"json data after array",
+			JSONSource{},
+			"[\n  {\"question\": \"5+5\", \"answer\": \"10\"}\n]\njunk",
+			4,
+		},
+		{
+			"json unclosed array",
+			JSONSource{},
+			"[\n  {\"question\": \"5+5\", \"answer\": \"10\"}\n",
+			3,
+		},
+		{
+			"jsonl syntax",
+			JSONLSource{},
+			"{\"question\": \"5+5\", \"answer\": \"10\"}\n{\"question\": \n",
+			2,
+		},
+		{
+			"yaml missing answer",
+			YAMLSource{},
+			"- question: 5+5\n  answer: 10\n- question: 1+1\n",
+			3,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.source.Parse(strings.NewReader(test.input))
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want ParseError", err)
+			}
+			if parseErr.Line != test.line {
+				t.Errorf("got line %d, want %d", parseErr.Line, test.line)
+			}
+		})
+	}
+}
+
+func TestSourceFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		fileName string
+		want     ProblemSource
+		wantErr  bool
+	}{
+		{"csv extension", "", "problems.csv", CSVSource{}, false},
+		{"yml extension", "", "problems.YML", YAMLSource{}, false},
+		{"ndjson extension", "", "problems.ndjson", JSONLSource{}, false},
+		{"format overrides extension", "json", "problems.txt", JSONSource{}, false},
+		{"unknown extension", "", "problems.txt", nil, true},
+		{"unknown format", "xml", "problems.csv", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sourceFor(test.format, test.fileName)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("got %T, want %T", got, test.want)
+			}
+		})
+	}
+}
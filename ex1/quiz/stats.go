@@ -0,0 +1,222 @@
+package quiz
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// QuestionStats summarizes answers to a single question across
+// all attempts
+type QuestionStats struct {
+	Question string
+	Asked    int
+	Correct  int
+	TimedOut int
+	// total latency of all answers, used to calculate average
+	latency time.Duration
+}
+
+// Accuracy returns the share of correct answers
+func (qs QuestionStats) Accuracy() float64 {
+	if qs.Asked == 0 {
+		return 0
+	}
+	return float64(qs.Correct) / float64(qs.Asked)
+}
+
+// AvgLatency returns average response time
+func (qs QuestionStats) AvgLatency() time.Duration {
+	if qs.Asked == 0 {
+		return 0
+	}
+	return qs.latency / time.Duration(qs.Asked)
+}
+
+// Stats calculates statistics of every question in the order
+// questions were first asked
+func Stats(attempts []Attempt) []QuestionStats {
+	var stats []QuestionStats
+	index := make(map[string]int)
+
+	for _, a := range attempts {
+		for _, ans := range a.Answers {
+			i, ok := index[ans.Question]
+			if !ok {
+				i = len(stats)
+				index[ans.Question] = i
+				stats = append(stats, QuestionStats{Question: ans.Question})
+			}
+
+			qs := &stats[i]
+			qs.Asked++
+			qs.latency += ans.Latency
+			if ans.Correct {
+				qs.Correct++
+			}
+			if ans.TimedOut {
+				qs.TimedOut++
+			}
+		}
+	}
+
+	return stats
+}
+
+// Hardest returns n questions with the lowest accuracy, slower
+// average response breaks ties
+func Hardest(stats []QuestionStats, n int) []QuestionStats {
+	hardest := make([]QuestionStats, len(stats))
+	copy(hardest, stats)
+
+	sort.SliceStable(hardest, func(i, j int) bool {
+		if hardest[i].Accuracy() != hardest[j].Accuracy() {
+			return hardest[i].Accuracy() < hardest[j].Accuracy()
+		}
+		return hardest[i].AvgLatency() > hardest[j].AvgLatency()
+	})
+
+	if n < len(hardest) {
+		hardest = hardest[:n]
+	}
+	return hardest
+}
+
+const defaultHardest = 3
+
+// statsEnv represents parsed arguments of stats subcommand
+type statsEnv struct {
+	history  string
+	fileName string
+	hardest  int
+	out      io.Writer
+}
+
+// statsCLI runs stats subcommand and returns its exit status
+func statsCLI(args []string) int {
+	app := statsEnv{out: os.Stdout}
+
+	err := app.fromArgs(args)
+	if err != nil {
+		return 2
+	}
+
+	if err = app.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func (app *statsEnv) fromArgs(args []string) error {
+	fl := flag.NewFlagSet("quiz stats", flag.ContinueOnError)
+	fl.StringVar(
+		&app.history, "history", defaultHistory, "a database file with results history",
+	)
+	fl.StringVar(
+		&app.fileName, "file", "", "show statistics only for attempts of this problems file",
+	)
+	fl.IntVar(
+		&app.hardest, "hardest", defaultHardest, "the number of hardest questions to show",
+	)
+
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+
+	if app.hardest < 0 {
+		fmt.Fprintf(os.Stderr, "got bad number of hardest questions: %v\n", app.hardest)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	return nil
+}
+
+func (app *statsEnv) run() error {
+	if _, err := os.Stat(app.history); err != nil {
+		return err
+	}
+
+	h, err := NewBoltHistory(app.history)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	attempts, err := h.GetAll()
+	if err != nil {
+		return err
+	}
+
+	if app.fileName != "" {
+		hash, err := hashFile(app.fileName)
+		if err != nil {
+			return err
+		}
+		attempts = filterAttempts(attempts, hash)
+	}
+
+	if len(attempts) == 0 {
+		fmt.Fprintln(app.out, "No attempts yet.")
+		return nil
+	}
+
+	app.print(attempts)
+
+	return nil
+}
+
+// print writes statistics table and hardest questions
+func (app *statsEnv) print(attempts []Attempt) {
+	correct, total := 0, 0
+	for _, a := range attempts {
+		correct += a.Score.Correct
+		total += a.Score.Total
+	}
+	fmt.Fprintf(app.out, "Attempts: %v, average score: %.1f%%\n\n", len(attempts), percent(correct, total))
+
+	stats := Stats(attempts)
+
+	w := tabwriter.NewWriter(app.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "QUESTION\tASKED\tCORRECT\tTIMED OUT\tACCURACY\tAVG TIME")
+	for _, qs := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.1f%%\t%v\n",
+			qs.Question, qs.Asked, qs.Correct, qs.TimedOut, qs.Accuracy()*100, qs.AvgLatency().Round(time.Millisecond))
+	}
+	w.Flush()
+
+	if app.hardest == 0 {
+		return
+	}
+
+	fmt.Fprintln(app.out, "\nHardest questions:")
+	for i, qs := range Hardest(stats, app.hardest) {
+		fmt.Fprintf(app.out, "%d. %s (%.1f%% correct, %v on average)\n",
+			i+1, qs.Question, qs.Accuracy()*100, qs.AvgLatency().Round(time.Millisecond))
+	}
+}
+
+// filterAttempts returns attempts of the problems file with given hash
+func filterAttempts(attempts []Attempt, hash string) []Attempt {
+	var filtered []Attempt
+	for _, a := range attempts {
+		if a.FileHash == hash {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+// percent returns part of total in percents
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
@@ -0,0 +1,71 @@
+package quiz
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAttempts = []Attempt{
+	{
+		Score: Score{Correct: 2, Total: 3},
+		Answers: []Answer{
+			{Question: "5+5", Correct: true, Latency: time.Second},
+			{Question: "7*8", Correct: false, Latency: 4 * time.Second},
+			{Question: "1+1", Correct: true, Latency: time.Second},
+		},
+	},
+	{
+		Score: Score{Correct: 1, TimedOut: 1, Total: 3},
+		Answers: []Answer{
+			{Question: "5+5", Correct: true, Latency: 3 * time.Second},
+			{Question: "7*8", TimedOut: true, Latency: 6 * time.Second},
+			{Question: "1+1", Correct: false, Latency: time.Second},
+		},
+	},
+}
+
+func TestStats(t *testing.T) {
+	want := []QuestionStats{
+		{Question: "5+5", Asked: 2, Correct: 2, latency: 4 * time.Second},
+		{Question: "7*8", Asked: 2, TimedOut: 1, latency: 10 * time.Second},
+		{Question: "1+1", Asked: 2, Correct: 1, latency: 2 * time.Second},
+	}
+
+	got := Stats(testAttempts)
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+
+	if avg := got[1].AvgLatency(); avg != 5*time.Second {
+		t.Errorf("got average latency %v, want 5s", avg)
+	}
+	if acc := got[2].Accuracy(); acc != 0.5 {
+		t.Errorf("got accuracy %v, want 0.5", acc)
+	}
+}
+
+func TestHardest(t *testing.T) {
+	got := Hardest(Stats(testAttempts), 2)
+	if len(got) != 2 || got[0].Question != "7*8" || got[1].Question != "1+1" {
+		t.Errorf("got %+v, want 7*8 and 1+1", got)
+	}
+}
+
+func TestStatsPrint(t *testing.T) {
+	var out bytes.Buffer
+	app := statsEnv{hardest: 1, out: &out}
+	app.print(testAttempts)
+
+	for _, want := range []string{"Attempts: 2, average score: 50.0%", "7*8", "Hardest questions:\n1. 7*8 (0.0% correct, 5s on average)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got output %q, want it to contain %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// PauseCommand is typed instead of the answer to pause the quiz
+	PauseCommand = "/pause"
+	// HintCommand is typed instead of the answer to show the hint
+	HintCommand = "/hint"
+)
+
+// Terminal runs quiz session reading answers from In and
+// writing problems and results to Out
+type Terminal struct {
+	In  io.Reader
+	Out io.Writer
+	// Live enables redrawing of the countdown every second,
+	// it should be used only if Out is a terminal
+	Live bool
+	// Review lists wrong answers with explanations after the quiz
+	Review bool
+}
+
+// NewTerminal creates Terminal on top of standard input and output
+func NewTerminal() Terminal {
+	return Terminal{
+		In:     os.Stdin,
+		Out:    os.Stdout,
+		Live:   isTerminal(os.Stdout),
+		Review: true,
+	}
+}
+
+// Run asks session problems until it is finished
+func (t Terminal) Run(ctx context.Context, s *Session) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines, errCh := readLines(ctx, t.In)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var deadline time.Time
+	// closed is set when input has ended
+	closed := false
+	for {
+		select {
+		case e := <-s.Events():
+			switch e.Kind {
+			case EventQuestion:
+				deadline = e.Deadline
+				t.printPrompt(e.Index+1, e.Problem, deadline)
+			case EventTimeout:
+				fmt.Fprintf(t.Out, "\nTime is up for this problem (%vs).\n", e.Result.Duration.Round(time.Second).Seconds())
+			case EventFinished:
+				t.printScore(s, e.Reason)
+				if t.Review {
+					t.printReview(s)
+				}
+				if e.Reason == ReasonCompleted && !closed {
+					fmt.Fprint(t.Out, "Press enter to quit...")
+					select {
+					case <-lines:
+					case <-errCh:
+					case <-ctx.Done():
+					}
+				}
+				return nil
+			}
+		case answer := <-lines:
+			if s.Paused() {
+				s.Resume()
+				deadline = s.Deadline()
+				if index, p, ok := s.Next(); ok {
+					t.printPrompt(index+1, p, deadline)
+				}
+				continue
+			}
+			if answer == HintCommand {
+				t.printHint(s)
+				continue
+			}
+			if answer == PauseCommand {
+				if s.Pause() {
+					deadline = time.Time{}
+					fmt.Fprint(t.Out, "Paused, press enter to continue...")
+				}
+				continue
+			}
+
+			// late answers to timed out problem or finished quiz
+			// are ignored
+			_, _ = s.Submit(answer)
+		case err := <-errCh:
+			closed = true
+			s.Stop()
+			if err != io.EOF {
+				return err
+			}
+		case <-ticker.C:
+			if t.Live && !deadline.IsZero() {
+				t.printCountdown(deadline)
+			}
+		}
+	}
+}
+
+// printScore prints final score explaining why the quiz has finished
+func (t Terminal) printScore(s *Session, reason Reason) {
+	score := s.Score()
+
+	switch reason {
+	case ReasonTimeUp:
+		fmt.Fprintf(t.Out, "\nTime ran out (%vs). ", s.Quiz().Limit.Seconds())
+	case ReasonCanceled:
+		fmt.Fprint(t.Out, "\nProgram interrupted. ")
+	case ReasonStopped:
+		fmt.Fprint(t.Out, "\nInput closed. ")
+	}
+
+	fmt.Fprintf(t.Out, "You scored %v out of %v", score.Points, score.Total)
+	if score.TimedOut > 0 {
+		fmt.Fprintf(t.Out, ", %v timed out", score.TimedOut)
+	}
+	switch {
+	case score.Hints == 1:
+		fmt.Fprint(t.Out, ", 1 hint used")
+	case score.Hints > 1:
+		fmt.Fprintf(t.Out, ", %v hints used", score.Hints)
+	}
+	fmt.Fprintln(t.Out, ".")
+
+	if s.Quiz().randomized() {
+		fmt.Fprintf(t.Out, "Seed: %v\n", s.Seed())
+	}
+}
+
+// printReview lists wrong and timed out answers with the expected
+// answers and explanations
+func (t Terminal) printReview(s *Session) {
+	first := true
+	for i, res := range s.Results() {
+		if res.Correct {
+			continue
+		}
+		if first {
+			fmt.Fprintln(t.Out, "\nReview:")
+			first = false
+		}
+
+		fmt.Fprintf(t.Out, "Problem #%d: %s\n", i+1, res.Problem.Question)
+		if res.TimedOut {
+			fmt.Fprintln(t.Out, "  Your answer: (timed out)")
+		} else {
+			fmt.Fprintf(t.Out, "  Your answer: %s\n", res.Answer)
+		}
+		fmt.Fprintf(t.Out, "  Correct answer: %s\n", res.Problem.ExpectedAnswer())
+		if res.Problem.Explanation != "" {
+			fmt.Fprintf(t.Out, "  Explanation: %s\n", res.Problem.Explanation)
+		}
+	}
+}
+
+// printHint shows hint of the current problem and asks it again
+func (t Terminal) printHint(s *Session) {
+	hint, err := s.Hint()
+	switch {
+	case errors.Is(err, ErrNoHint):
+		fmt.Fprintln(t.Out, "There is no hint for this problem.")
+	case err != nil:
+		return
+	default:
+		fmt.Fprintf(t.Out, "Hint: %s\n", hint)
+	}
+
+	if index, p, ok := s.Next(); ok {
+		t.printPrompt(index+1, p, s.Deadline())
+	}
+}
+
+// printPrompt prints problem with the countdown in front of the
+// line where the answer is typed
+func (t Terminal) printPrompt(num int, p Problem, deadline time.Time) {
+	prompt := fmt.Sprintf("Problem #%v: %v", num, p.Prompt())
+	if deadline.IsZero() {
+		fmt.Fprint(t.Out, prompt)
+		return
+	}
+
+	i := strings.LastIndex(prompt, "\n") + 1
+	fmt.Fprintf(t.Out, "%s%s %s", prompt[:i], countdown(deadline), prompt[i:])
+}
+
+// printCountdown redraws the countdown at the beginning of the
+// current line keeping the cursor where user types
+func (t Terminal) printCountdown(deadline time.Time) {
+	fmt.Fprintf(t.Out, "\0337\r%s\0338", countdown(deadline))
+}
+
+// countdown formats seconds left until deadline
+func countdown(deadline time.Time) string {
+	left := time.Until(deadline).Round(time.Second)
+	if left < 0 {
+		left = 0
+	}
+	return fmt.Sprintf("[%3.0fs]", left.Seconds())
+}
+
+// isTerminal reports whether w is a character device
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,132 @@
+package quiz
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTerminalRun(t *testing.T) {
+	problems := []Problem{
+		{Question: "5+5", Answer: "10"},
+		{Question: "Capital of France", Answer: "Paris"},
+		{Question: "1+1", Answer: "2"},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"all answered",
+			"10\n paris \n3\n",
+			"You scored 2 out of 3.",
+		},
+		{
+			"input closed",
+			"10\n",
+			"Input closed. You scored 1 out of 3.",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			term := Terminal{In: strings.NewReader(test.input), Out: &out}
+			quiz := Quiz{Problems: problems, Policy: DefaultPolicy, Limit: 5 * time.Second}
+
+			if err := term.Run(context.Background(), quiz.Start(context.Background())); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(out.String(), test.want) {
+				t.Errorf("got output %q, want it to contain %q", out.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestTerminalRunInterrupted(t *testing.T) {
+	// pipe is never written, so the quiz waits for the answer
+	r, w := io.Pipe()
+	defer w.Close()
+
+	var out bytes.Buffer
+	term := Terminal{In: r, Out: &out}
+	quiz := Quiz{Problems: []Problem{{Question: "5+5", Answer: "10"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- term.Run(ctx, quiz.Start(ctx))
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("run didn't return after context was canceled")
+	}
+
+	if want := "Program interrupted. You scored 0 out of 1."; !strings.Contains(out.String(), want) {
+		t.Errorf("got output %q, want it to contain %q", out.String(), want)
+	}
+}
+
+func TestTerminalRunQuestionTimeout(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	var out bytes.Buffer
+	term := Terminal{In: r, Out: &out}
+	quiz := Quiz{
+		Problems:      []Problem{{Question: "5+5", Answer: "10"}, {Question: "1+1", Answer: "2"}},
+		QuestionLimit: 20 * time.Millisecond,
+	}
+
+	// input is never closed, so the context stops waiting for enter
+	// after the quiz is completed
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := term.Run(ctx, quiz.Start(context.Background())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "You scored 0 out of 2, 2 timed out."; !strings.Contains(out.String(), want) {
+		t.Errorf("got output %q, want it to contain %q", out.String(), want)
+	}
+}
+
+func TestTerminalHintAndReview(t *testing.T) {
+	problems := []Problem{
+		{Question: "5+5", Answer: "10", Hint: "two fives"},
+		{Question: "1+1", Answer: "2", Explanation: "one and one make two"},
+	}
+
+	var out bytes.Buffer
+	term := Terminal{In: strings.NewReader(HintCommand + "\n10\n3\n\n"), Out: &out, Review: true}
+	quiz := Quiz{Problems: problems, Policy: DefaultPolicy, HintPenalty: 0.5}
+
+	if err := term.Run(context.Background(), quiz.Start(context.Background())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"Hint: two fives\nProblem #1: 5+5 = ",
+		"You scored 0.5 out of 2, 1 hint used.",
+		"Review:\nProblem #2: 1+1\n  Your answer: 3\n  Correct answer: 2\n  Explanation: one and one make two\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got output %q, want it to contain %q", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "Problem #1: 5+5\n") {
+		t.Error("got correct answer in review")
+	}
+}
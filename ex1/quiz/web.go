@@ -0,0 +1,310 @@
+package quiz
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//go:embed web.html
+var webTemplate string
+
+const (
+	sessionCookie = "quiz_session"
+	// sessionTTL is how long sessions are kept after they are
+	// finished to show their results, unfinished sessions are kept
+	// as long after the last request of the visitor
+	sessionTTL = time.Hour
+)
+
+// WebServer runs quiz sessions over HTTP, every visitor gets its
+// own session identified by cookie
+type WebServer struct {
+	Quiz Quiz
+	// OnFinish is called in separate goroutine when session is
+	// finished, it may be nil
+	OnFinish func(s *Session)
+
+	tmpl *template.Template
+	ctx  context.Context
+
+	mu       sync.Mutex
+	sessions map[string]*webSession
+}
+
+// webSession is a quiz session of a single visitor
+type webSession struct {
+	*Session
+	// active is the time of the last request of the visitor
+	active time.Time
+}
+
+// NewWebServer creates WebServer, sessions are canceled when ctx is done
+func NewWebServer(ctx context.Context, q Quiz) *WebServer {
+	tmpl := template.Must(template.New("quiz").Funcs(template.FuncMap{
+		"inc": func(i int) int { return i + 1 },
+	}).Parse(webTemplate))
+
+	return &WebServer{
+		Quiz:     q,
+		tmpl:     tmpl,
+		ctx:      ctx,
+		sessions: make(map[string]*webSession),
+	}
+}
+
+// Handler returns http.Handler serving quiz pages
+func (ws *WebServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", ws.index)
+	mux.HandleFunc("/start", ws.start)
+	mux.HandleFunc("/question", ws.question)
+	mux.HandleFunc("/answer", ws.answer)
+	mux.HandleFunc("/results", ws.results)
+	return mux
+}
+
+// runWeb serves quiz over HTTP until ctx is done
+func (app *appEnv) runWeb(ctx context.Context, q Quiz) error {
+	ws := NewWebServer(ctx, q)
+	ws.OnFinish = func(s *Session) {
+		if err := app.record(s); err != nil {
+			log.Println(err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:    app.addr,
+		Handler: ws.Handler(),
+	}
+
+	// Launch server
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Starting the server on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	// Wait for interrupt signal to close http server
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		log.Println("Program interrupted")
+	}
+
+	return srv.Shutdown(context.Background())
+}
+
+// index shows start page
+func (ws *WebServer) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if s := ws.session(r); s != nil {
+		http.Redirect(w, r, "/question", http.StatusFound)
+		return
+	}
+
+	ws.render(w, "start", map[string]interface{}{
+		"Total": ws.Quiz.size(),
+		"Limit": ws.Quiz.Limit,
+	})
+}
+
+// start creates new session for the visitor
+func (ws *WebServer) start(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s := ws.Quiz.Start(ws.ctx)
+	if ws.OnFinish != nil {
+		go func() {
+			<-s.Done()
+			ws.OnFinish(s)
+		}()
+	}
+
+	ws.mu.Lock()
+	ws.cleanupLocked()
+	ws.sessions[id] = &webSession{Session: s, active: time.Now()}
+	ws.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/question", http.StatusSeeOther)
+}
+
+// question shows the current problem of the session
+func (ws *WebServer) question(w http.ResponseWriter, r *http.Request) {
+	s := ws.session(r)
+	if s == nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+
+	index, p, ok := s.Next()
+	if !ok {
+		http.Redirect(w, r, "/results", http.StatusFound)
+		return
+	}
+
+	type option struct {
+		Letter string
+		Text   string
+	}
+	var options []option
+	if p.kind() == KindChoice {
+		for i, opt := range p.Options {
+			options = append(options, option{Letter: string(optionLetter(i)), Text: opt})
+		}
+	}
+
+	data := map[string]interface{}{
+		"Number":  index + 1,
+		"Total":   s.Score().Total,
+		"Problem": p,
+		"Options": options,
+	}
+	// reload the page when time is up, so the server moves on
+	if deadline := s.Deadline(); !deadline.IsZero() {
+		left := time.Until(deadline).Round(time.Second)
+		data["Left"] = left
+		data["Refresh"] = int(left.Seconds()) + 1
+	}
+
+	ws.render(w, "question", data)
+}
+
+// answer submits the answer to the current problem
+func (ws *WebServer) answer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := ws.session(r)
+	if s == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// late answers to timed out problem or finished quiz
+	// are ignored
+	_, _ = s.Submit(r.PostFormValue("answer"))
+
+	http.Redirect(w, r, "/question", http.StatusSeeOther)
+}
+
+// results shows the score and results of every problem
+func (ws *WebServer) results(w http.ResponseWriter, r *http.Request) {
+	s := ws.session(r)
+	if s == nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+
+	reason, ok := s.Finished()
+	if !ok {
+		http.Redirect(w, r, "/question", http.StatusFound)
+		return
+	}
+
+	message := "Quiz completed"
+	switch reason {
+	case ReasonTimeUp:
+		message = "Time ran out"
+	case ReasonCanceled, ReasonStopped:
+		message = "Quiz stopped"
+	}
+
+	data := map[string]interface{}{
+		"Message": message,
+		"Score":   s.Score(),
+		"Results": s.Results(),
+	}
+	if ws.Quiz.randomized() {
+		data["Seed"] = s.Seed()
+	}
+
+	ws.render(w, "results", data)
+}
+
+// session returns session of the visitor or nil
+func (ws *WebServer) session(r *http.Request) *webSession {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	s := ws.sessions[c.Value]
+	if s != nil {
+		s.active = time.Now()
+	}
+	return s
+}
+
+// cleanupLocked stops and removes sessions finished more than
+// sessionTTL ago and unfinished ones without requests for as long
+func (ws *WebServer) cleanupLocked() {
+	now := time.Now()
+	for id, s := range ws.sessions {
+		if s.expired(now) {
+			s.Stop()
+			delete(ws.sessions, id)
+		}
+	}
+}
+
+// expired reports whether session is kept for longer than
+// sessionTTL at now
+func (s *webSession) expired(now time.Time) bool {
+	since := s.active
+	if finished := s.FinishedAt(); !finished.IsZero() {
+		since = finished
+	}
+	return now.Sub(since) > sessionTTL
+}
+
+// render executes template by name
+func (ws *WebServer) render(w http.ResponseWriter, name string, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := ws.tmpl.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// newSessionID generates random session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,127 @@
+package quiz
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer(t *testing.T) {
+	quiz := Quiz{
+		Problems: []Problem{
+			{Question: "Two plus two", Answer: "4"},
+			{Kind: KindChoice, Question: "Capital of France?", Options: []string{"London", "Paris"}, Answer: "Paris"},
+		},
+		Policy: DefaultPolicy,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := httptest.NewServer(NewWebServer(ctx, quiz).Handler())
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Jar: jar}
+
+	body := get(t, client, srv.URL+"/")
+	if !strings.Contains(body, "2 problems") {
+		t.Errorf("start page doesn't show number of problems: %q", body)
+	}
+
+	body = post(t, client, srv.URL+"/start", nil)
+	if !strings.Contains(body, "Problem #1 of 2") || !strings.Contains(body, "Two plus two") {
+		t.Errorf("got unexpected first problem page: %q", body)
+	}
+
+	body = post(t, client, srv.URL+"/answer", url.Values{"answer": {"4"}})
+	if !strings.Contains(body, "Problem #2 of 2") || !strings.Contains(body, `value="b"`) {
+		t.Errorf("got unexpected second problem page: %q", body)
+	}
+
+	body = post(t, client, srv.URL+"/answer", url.Values{"answer": {"a"}})
+	if !strings.Contains(body, "Quiz completed") || !strings.Contains(body, "You scored 1 out of 2.") {
+		t.Errorf("got unexpected results page: %q", body)
+	}
+}
+
+func TestWebServerWithoutSession(t *testing.T) {
+	srv := httptest.NewServer(NewWebServer(context.Background(), Quiz{}).Handler())
+	defer srv.Close()
+
+	body := get(t, http.DefaultClient, srv.URL+"/question")
+	if !strings.Contains(body, "Start") {
+		t.Errorf("request without session wasn't redirected to start page: %q", body)
+	}
+}
+
+func TestWebSessionExpired(t *testing.T) {
+	quiz := Quiz{Problems: []Problem{{Question: "Two plus two", Answer: "4"}}, Policy: DefaultPolicy}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// without quiz time limit unfinished session is kept while
+	// the visitor is active
+	s := &webSession{Session: quiz.Start(ctx), active: time.Now()}
+	if s.expired(time.Now().Add(sessionTTL / 2)) {
+		t.Error("active session expired")
+	}
+	if !s.expired(time.Now().Add(2 * sessionTTL)) {
+		t.Error("abandoned session didn't expire")
+	}
+
+	// finished session is kept for sessionTTL after it is finished
+	s.active = time.Now().Add(-2 * sessionTTL)
+	s.Stop()
+	if s.expired(time.Now().Add(sessionTTL / 2)) {
+		t.Error("recently finished session expired")
+	}
+	if !s.expired(time.Now().Add(2 * sessionTTL)) {
+		t.Error("finished session didn't expire")
+	}
+}
+
+func get(t *testing.T, client *http.Client, addr string) string {
+	t.Helper()
+
+	resp, err := client.Get(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readBody(t, resp)
+}
+
+func post(t *testing.T, client *http.Client, addr string, form url.Values) string {
+	t.Helper()
+
+	resp, err := client.PostForm(addr, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readBody(t, resp)
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v", resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
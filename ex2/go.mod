@@ -3,6 +3,7 @@ module github.com/semka95/gophercises/ex2
 go 1.14
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.6
 	go.etcd.io/bbolt v1.3.5
 	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
 )
@@ -0,0 +1,342 @@
+package urlshort
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	hitsBucket = "hits"
+	// hitsQueue is the number of hits waiting to be saved, hits
+	// are dropped when the queue is full so redirects never wait
+	hitsQueue = 1024
+	// hitsBatch is the maximum number of hits saved in single
+	// transaction
+	hitsBatch = 128
+	// topReferrers is the number of referrers in link stats
+	topReferrers = 5
+)
+
+// Hit is a single redirect of the link
+type Hit struct {
+	Path      string    `json:"path"`
+	Time      time.Time `json:"time"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// IP is the client network, the last part of the address is
+	// zeroed for privacy
+	IP string `json:"ip,omitempty"`
+}
+
+// Analytics saves hits to BoltDB in background
+type Analytics struct {
+	db    *bolt.DB
+	hits  chan Hit
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	drops int
+}
+
+// NewAnalytics creates Analytics and starts saving hits
+func NewAnalytics(db *bolt.DB) (*Analytics, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(hitsBucket))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Analytics{
+		db:   db,
+		hits: make(chan Hit, hitsQueue),
+	}
+	a.wg.Add(1)
+	go a.save()
+
+	return a, nil
+}
+
+// Record queues hit to be saved, it never blocks
+func (a *Analytics) Record(h Hit) {
+	select {
+	case a.hits <- h:
+	default:
+		a.mu.Lock()
+		a.drops++
+		a.mu.Unlock()
+	}
+}
+
+// Close saves queued hits and stops Analytics, Record must not be
+// called after Close
+func (a *Analytics) Close() {
+	close(a.hits)
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.drops > 0 {
+		log.Printf("analytics: %d hits were dropped", a.drops)
+	}
+}
+
+// save writes queued hits to database in batches
+func (a *Analytics) save() {
+	defer a.wg.Done()
+
+	batch := make([]Hit, 0, hitsBatch)
+	for h := range a.hits {
+		batch = append(batch[:0], h)
+		// take everything already queued without waiting
+	drain:
+		for len(batch) < hitsBatch {
+			select {
+			case h, ok := <-a.hits:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, h)
+			default:
+				break drain
+			}
+		}
+
+		if err := storeHits(a.db, batch); err != nil {
+			log.Printf("analytics: %v", err)
+		}
+	}
+}
+
+// storeHits puts hits to nested bucket of their path
+func storeHits(db *bolt.DB, hits []Hit) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(hitsBucket))
+		for _, h := range hits {
+			b, err := root.CreateBucketIfNotExists([]byte(h.Path))
+			if err != nil {
+				return err
+			}
+
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			buf, err := json.Marshal(h)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(seqKey(seq), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// seqKey returns an 8-byte big endian representation of seq
+func seqKey(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
+
+// matchedKey is the context key of matchedLink
+type matchedKey struct{}
+
+// matchedLink is the path of the link which redirected the
+// request, it is set by StoreHandler
+type matchedLink struct {
+	path string
+}
+
+// setMatched remembers path of the link which redirected the
+// request for TrackHandler
+func setMatched(r *http.Request, path string) {
+	if m, ok := r.Context().Value(matchedKey{}).(*matchedLink); ok {
+		m.path = path
+	}
+}
+
+// TrackHandler records hit of every redirect made by StoreHandler
+// in next handler. Hits are recorded under the path of the link,
+// so all requests matching pattern link are counted together.
+func TrackHandler(a *Analytics, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m := &matchedLink{}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), matchedKey{}, m)))
+
+		if m.path == "" || sw.status < 300 || sw.status >= 400 {
+			return
+		}
+		a.Record(Hit{
+			Path:      m.path,
+			Time:      time.Now().UTC(),
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			IP:        coarseIP(r.RemoteAddr),
+		})
+	}
+}
+
+// statusWriter remembers status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// coarseIP returns /24 network of IPv4 or /48 network of IPv6
+// client address
+func coarseIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// LinkClicks is the number of hits of the link
+type LinkClicks struct {
+	Path   string `json:"path"`
+	Clicks int    `json:"clicks"`
+}
+
+// Bucket is the number of hits in the interval starting at Time
+type Bucket struct {
+	Time   time.Time `json:"time"`
+	Clicks int       `json:"clicks"`
+}
+
+// ReferrerClicks is the number of hits from the referrer
+type ReferrerClicks struct {
+	Referrer string `json:"referrer"`
+	Clicks   int    `json:"clicks"`
+}
+
+// LinkStats is hits of the link grouped by time intervals and
+// referrers
+type LinkStats struct {
+	Path         string           `json:"path"`
+	Clicks       int              `json:"clicks"`
+	Buckets      []Bucket         `json:"buckets"`
+	TopReferrers []ReferrerClicks `json:"top_referrers"`
+}
+
+// intervals maps name of stats interval to its duration
+var intervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// clickCounts returns the number of hits of every link, the most
+// clicked first
+func clickCounts(db *bolt.DB) ([]LinkClicks, error) {
+	counts := []LinkClicks{}
+	err := db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(hitsBucket))
+		if root == nil {
+			return nil
+		}
+		return root.ForEach(func(k, _ []byte) error {
+			n := root.Bucket(k).Stats().KeyN
+			counts = append(counts, LinkClicks{Path: string(k), Clicks: n})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Clicks > counts[j].Clicks
+	})
+	return counts, nil
+}
+
+// linkStats returns stats of the path with hits grouped by interval,
+// only hits since given time are counted
+func linkStats(db *bolt.DB, path string, interval time.Duration, since time.Time) (LinkStats, error) {
+	stats := LinkStats{Path: path, Buckets: []Bucket{}, TopReferrers: []ReferrerClicks{}}
+	buckets := make(map[time.Time]int)
+	referrers := make(map[string]int)
+
+	err := db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(hitsBucket))
+		if root == nil || root.Bucket([]byte(path)) == nil {
+			return ErrNotFound
+		}
+
+		return root.Bucket([]byte(path)).ForEach(func(_, v []byte) error {
+			var h Hit
+			if err := json.Unmarshal(v, &h); err != nil {
+				return err
+			}
+			if h.Time.Before(since) {
+				return nil
+			}
+
+			stats.Clicks++
+			buckets[h.Time.Truncate(interval)]++
+			if h.Referrer != "" {
+				referrers[h.Referrer]++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	for t, n := range buckets {
+		stats.Buckets = append(stats.Buckets, Bucket{Time: t, Clicks: n})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Time.Before(stats.Buckets[j].Time)
+	})
+
+	for ref, n := range referrers {
+		stats.TopReferrers = append(stats.TopReferrers, ReferrerClicks{Referrer: ref, Clicks: n})
+	}
+	sort.Slice(stats.TopReferrers, func(i, j int) bool {
+		if stats.TopReferrers[i].Clicks != stats.TopReferrers[j].Clicks {
+			return stats.TopReferrers[i].Clicks > stats.TopReferrers[j].Clicks
+		}
+		return stats.TopReferrers[i].Referrer < stats.TopReferrers[j].Referrer
+	})
+	if len(stats.TopReferrers) > topReferrers {
+		stats.TopReferrers = stats.TopReferrers[:topReferrers]
+	}
+
+	return stats, nil
+}
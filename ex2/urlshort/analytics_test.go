@@ -0,0 +1,182 @@
+package urlshort
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoarseIP(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"192.168.1.42:5555", "192.168.1.0"},
+		{"[2001:db8:1234:5678::1]:443", "2001:db8:1234::"},
+		{"10.0.0.7", "10.0.0.0"},
+		{"bad", ""},
+	}
+	for _, test := range tests {
+		if got := coarseIP(test.addr); got != test.want {
+			t.Errorf("coarseIP(%q) = %q, want %q", test.addr, got, test.want)
+		}
+	}
+}
+
+func TestTrackHandler(t *testing.T) {
+	db := openTestDB(t)
+	a, err := NewAnalytics(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	links := MapHandler(map[string]string{"/go": "https://golang.org"}, http.NotFoundHandler())
+	h := TrackHandler(a, links)
+
+	for i, ref := range []string{"https://a.example", "https://b.example", "https://a.example", ""} {
+		r := httptest.NewRequest(http.MethodGet, "/go", nil)
+		r.RemoteAddr = "192.168.1.42:5555"
+		if ref != "" {
+			r.Header.Set("Referer", ref)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusFound {
+			t.Fatalf("request #%d: got status %d, want redirect", i+1, w.Code)
+		}
+	}
+	// not found paths are not recorded
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	a.Close()
+
+	counts, err := clickCounts(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counts) != 1 || counts[0] != (LinkClicks{Path: "/go", Clicks: 4}) {
+		t.Errorf("got counts %+v, want 4 clicks of /go", counts)
+	}
+
+	stats, err := linkStats(db, "/go", time.Hour, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Clicks != 4 || len(stats.Buckets) == 0 {
+		t.Errorf("got stats %+v, want 4 clicks", stats)
+	}
+	if len(stats.TopReferrers) != 2 || stats.TopReferrers[0] != (ReferrerClicks{Referrer: "https://a.example", Clicks: 2}) {
+		t.Errorf("got referrers %+v, want a.example first", stats.TopReferrers)
+	}
+
+	stats, err = linkStats(db, "/go", time.Hour, time.Now().Add(time.Hour))
+	if err != nil || stats.Clicks != 0 {
+		t.Errorf("got %d clicks and error %v, want no clicks in the future", stats.Clicks, err)
+	}
+
+	if _, err := linkStats(db, "/missing", time.Hour, time.Time{}); err != ErrNotFound {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
+
+func TestTrackHandlerPattern(t *testing.T) {
+	db := openTestDB(t)
+	api, err := APIHandler(db, nil, DomainPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, err := NewAnalytics(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	links := &MapStore{links: linkMap([]Link{{Path: "/gh/{user}/{repo}", URL: "https://github.com/{user}/{repo}"}})}
+	// fallback redirects aren't links and are not recorded
+	fallback := http.RedirectHandler("/", http.StatusMovedPermanently)
+	h := TrackHandler(a, StoreHandler(links, fallback))
+	for _, path := range []string{"/gh/golang/go", "/gh/semka95/gophercises", "/other/"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	a.Close()
+
+	counts, err := clickCounts(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counts) != 1 || counts[0] != (LinkClicks{Path: "/gh/{user}/{repo}", Clicks: 2}) {
+		t.Errorf("got counts %+v, want 2 clicks of the pattern", counts)
+	}
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats/gh/%7Buser%7D/%7Brepo%7D", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"clicks":2`) {
+		t.Errorf("got %d %q, want stats of the pattern", w.Code, w.Body.String())
+	}
+}
+
+func TestParseStatsRange(t *testing.T) {
+	now := time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	d, since, err := parseStatsRange("hour", "24h", now)
+	if err != nil || d != time.Hour || !since.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("got %v, %v and error %v, want hour since a day ago", d, since, err)
+	}
+
+	d, since, err = parseStatsRange("", "2020-06-01T00:00:00Z", now)
+	if err != nil || d != 24*time.Hour || since.Day() != 1 {
+		t.Errorf("got %v, %v and error %v, want day since June 1", d, since, err)
+	}
+
+	for _, test := range [][2]string{{"month", ""}, {"day", "yesterday"}, {"day", "-1h"}} {
+		if _, _, err := parseStatsRange(test[0], test[1], now); err == nil {
+			t.Errorf("%v: expected error", test)
+		}
+	}
+}
+
+func TestAPIStats(t *testing.T) {
+	db := openTestDB(t)
+	api, err := APIHandler(db, nil, DomainPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, err := NewAnalytics(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.Close()
+	hit := Hit{Path: "/go", Time: time.Date(2020, 6, 15, 12, 30, 0, 0, time.UTC)}
+	if err := storeHits(db, []Hit{hit}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/api/stats", http.StatusOK, `[{"path":"/go","clicks":1}]`},
+		{"/api/stats/go?interval=day", http.StatusOK, `"buckets":[{"time":"2020-06-15T00:00:00Z","clicks":1}]`},
+		{"/api/stats/go?interval=month", http.StatusBadRequest, "unknown interval"},
+		{"/api/stats/missing", http.StatusNotFound, "not found"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.status || !strings.Contains(w.Body.String(), test.want) {
+			t.Errorf("%s: got %d %q, want %d %q", test.path, w.Code, w.Body.String(), test.status, test.want)
+		}
+	}
+}
+
+func TestStatsDBInUse(t *testing.T) {
+	db := openTestDB(t)
+
+	app := statsEnv{dbPath: db.Path(), interval: defaultInterval, out: ioutil.Discard}
+	if err := app.run(); !errors.Is(err, errDBInUse) {
+		t.Errorf("got error %v, want %v", err, errDBInUse)
+	}
+}
@@ -0,0 +1,278 @@
+package urlshort
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// apiPrefix is the root of admin REST API
+	apiPrefix = "/api/"
+	linksPath = "/api/links"
+	// maxBodySize limits size of API request body
+	maxBodySize = 1 << 20
+)
+
+// APIHandler returns http.Handler of admin REST API managing links
+// in BoltDB, changes are served by BoltHandler without restart:
+//
+//	GET    /api/links        list all links
+//	POST   /api/links        create link from {"path": "/path", "url": "url"},
+//	                         optional fields are expires_at, max_hits,
+//	                         disabled, status, cache_control and
+//	                         referrer_policy
+//	GET    /api/links/path   get link of /path
+//	PUT    /api/links/path   change link of /path from {"url": "url"}, its
+//	                         counted hits are kept
+//	DELETE /api/links/path   delete link of /path
+//	POST   /api/shorten      create link with generated short code from
+//	                         {"url": "url"} or {"url": "url", "alias": "code"}
+//	GET    /api/stats        click counts of all links
+//	GET    /api/stats/path   clicks of /path by interval, query parameters are
+//	                         interval (hour, day or week) and since
+//
+// URLs of created and changed links must be allowed by the policy.
+// Their paths must not conflict with links, which are all links
+// served next to BoltDB ones, nil means links of BoltDB only.
+func APIHandler(db *bolt.DB, links LinkStore, policy DomainPolicy) (http.Handler, error) {
+	shortener, err := NewShortener(db)
+	if err != nil {
+		return nil, err
+	}
+	shortener.Policy = policy
+	if links != nil {
+		shortener.Links = links
+	}
+
+	api := linksAPI{db: db, links: shortener.Links, shortener: shortener, policy: policy}
+	mux := http.NewServeMux()
+	mux.HandleFunc(linksPath, api.collection)
+	mux.HandleFunc(linksPath+"/", api.item)
+	mux.HandleFunc(shortenPath, api.shorten)
+	mux.HandleFunc(statsPath, api.stats)
+	mux.HandleFunc(statsPath+"/", api.stats)
+
+	return mux, nil
+}
+
+// TokenHandler returns http.Handler passing to h only requests
+// with "Authorization: Bearer <token>" header, others are
+// rejected with 401 Unauthorized
+func TokenHandler(token string, h http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if token == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, errUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// linksAPI serves admin REST API requests
+type linksAPI struct {
+	db        *bolt.DB
+	links     LinkStore
+	shortener *Shortener
+	policy    DomainPolicy
+}
+
+// collection handles requests to the list of links
+func (api linksAPI) collection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		links, err := listLinks(api.db)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, links)
+	case http.MethodPost:
+		var l Link
+		if err := readJSON(w, r, &l); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := checkLink(l, api.policy); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := checkConflict(api.links, l.Path); err != nil {
+			writeError(w, err)
+			return
+		}
+		// hits are counted by redirects only
+		l.Hits = 0
+
+		if err := createLink(api.db, l); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", linksPath+l.Path)
+		writeJSON(w, http.StatusCreated, l)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, errMethod)
+	}
+}
+
+// item handles requests to a single link, its path follows
+// links path, e.g. /api/links/bolt is the link of /bolt
+func (api linksAPI) item(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, linksPath)
+
+	switch r.Method {
+	case http.MethodGet:
+		l, err := getLink(api.db, path)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+	case http.MethodPut:
+		var l Link
+		if err := readJSON(w, r, &l); err != nil {
+			writeError(w, err)
+			return
+		}
+		if l.Path != "" && l.Path != path {
+			writeError(w, validationError{fmt.Errorf("path %q doesn't match %q", l.Path, path)})
+			return
+		}
+		l.Path = path
+		if err := checkLink(l, api.policy); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := checkConflict(api.links, l.Path); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if err := updateLink(api.db, l); err != nil {
+			writeError(w, err)
+			return
+		}
+		l, err := getLink(api.db, path)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+	case http.MethodDelete:
+		if err := deleteLink(api.db, path); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeError(w, errMethod)
+	}
+}
+
+var (
+	errMethod       = errors.New("method not allowed")
+	errUnauthorized = errors.New("unauthorized")
+)
+
+// validationError describes invalid request data
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e validationError) Unwrap() error {
+	return e.err
+}
+
+// validateLink checks that path can be redirected and URL is
+// absolute http or https URL
+func validateLink(l Link) error {
+	if !strings.HasPrefix(l.Path, "/") || l.Path == "/" {
+		return validationError{fmt.Errorf("path %q must start with / and not be empty", l.Path)}
+	}
+	if first := strings.SplitN(l.Path[1:], "/", 2)[0]; reserved[first] {
+		return validationError{fmt.Errorf("path %q is reserved", l.Path)}
+	}
+
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return validationError{fmt.Errorf("bad url: %v", err)}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationError{fmt.Errorf("url %q must be absolute http or https url", l.URL)}
+	}
+
+	if isPattern(l.Path) {
+		if err := validatePattern(l); err != nil {
+			return err
+		}
+	}
+
+	if l.MaxHits < 0 || l.Hits < 0 {
+		return validationError{fmt.Errorf("max hits and hits of %q must not be negative", l.Path)}
+	}
+
+	if err := validateRedirect(l); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readJSON decodes request body to v
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return validationError{fmt.Errorf("bad request body: %v", err)}
+	}
+	return nil
+}
+
+// writeJSON writes v as JSON response with given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+// writeError writes error as JSON response with status matching
+// the error
+func writeError(w http.ResponseWriter, err error) {
+	status, msg := http.StatusInternalServerError, err.Error()
+	var ve validationError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrExists):
+		status = http.StatusConflict
+	case errors.Is(err, errMethod):
+		status = http.StatusMethodNotAllowed
+	case errors.Is(err, errUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.As(err, &ve):
+		status = http.StatusBadRequest
+	default:
+		log.Println(err)
+		msg = http.StatusText(status)
+	}
+
+	writeJSON(w, status, map[string]string{"error": msg})
+}
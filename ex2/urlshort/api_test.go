@@ -0,0 +1,130 @@
+package urlshort
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTestDB opens empty BoltDB removed after the test
+func openTestDB(t *testing.T) *bolt.DB {
+	dir, err := ioutil.TempDir("", "urlshort")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	return db
+}
+
+func TestAPIHandler(t *testing.T) {
+	db := openTestDB(t)
+	api, err := APIHandler(db, nil, DomainPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	redirect, err := BoltHandler(db, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"empty list", http.MethodGet, "/api/links", "", http.StatusOK, "[]"},
+		{"create", http.MethodPost, "/api/links", `{"path": "/go", "url": "https://golang.org"}`, http.StatusCreated, `"url":"https://golang.org"`},
+		{"create existing", http.MethodPost, "/api/links", `{"path": "/go", "url": "https://go.dev"}`, http.StatusConflict, "already exists"},
+		{"create bad url", http.MethodPost, "/api/links", `{"path": "/bad", "url": "golang.org"}`, http.StatusBadRequest, "absolute"},
+		{"create api path", http.MethodPost, "/api/links", `{"path": "/api", "url": "https://go.dev"}`, http.StatusBadRequest, "reserved"},
+		{"create reserved path", http.MethodPost, "/api/links", `{"path": "/admin/users", "url": "https://go.dev"}`, http.StatusBadRequest, "reserved"},
+		{"create bad body", http.MethodPost, "/api/links", `{"path": "/bad"`, http.StatusBadRequest, "bad request body"},
+		{"get", http.MethodGet, "/api/links/go", "", http.StatusOK, `{"path":"/go","url":"https://golang.org"}`},
+		{"get missing", http.MethodGet, "/api/links/missing", "", http.StatusNotFound, "not found"},
+		{"update", http.MethodPut, "/api/links/go", `{"url": "https://go.dev"}`, http.StatusOK, `"url":"https://go.dev"`},
+		{"update missing", http.MethodPut, "/api/links/missing", `{"url": "https://go.dev"}`, http.StatusNotFound, "not found"},
+		{"update other path", http.MethodPut, "/api/links/go", `{"path": "/other", "url": "https://go.dev"}`, http.StatusBadRequest, "doesn't match"},
+		{"list", http.MethodGet, "/api/links", "", http.StatusOK, `[{"path":"/go","url":"https://go.dev"}]`},
+		{"method", http.MethodPatch, "/api/links", "", http.StatusMethodNotAllowed, "not allowed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+
+			if w.Code != test.status {
+				t.Errorf("got status %d, want %d", w.Code, test.status)
+			}
+			if !strings.Contains(w.Body.String(), test.want) {
+				t.Errorf("got body %q, want it to contain %q", w.Body.String(), test.want)
+			}
+		})
+	}
+
+	// updated link is redirected without restart
+	w := httptest.NewRecorder()
+	redirect.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/go", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://go.dev" {
+		t.Errorf("got status %d and location %q, want redirect to https://go.dev", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/links/go", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	w = httptest.NewRecorder()
+	redirect.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/go", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d for deleted link, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTokenHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		token  string
+		header string
+		status int
+	}{
+		{"secret", "Bearer secret", http.StatusNoContent},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodDelete, "/api/links/go", nil)
+		if test.header != "" {
+			r.Header.Set("Authorization", test.header)
+		}
+		w := httptest.NewRecorder()
+		TokenHandler(test.token, ok).ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("token %q, header %q: got status %d, want %d", test.token, test.header, w.Code, test.status)
+		}
+	}
+}
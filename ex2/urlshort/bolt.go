@@ -0,0 +1,261 @@
+package urlshort
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	linksBucket = "links"
+	// patternsBucket sequence is the version of pattern links
+	patternsBucket = "patterns"
+)
+
+var (
+	// ErrNotFound is returned when link with given path doesn't exist
+	ErrNotFound = errors.New("link not found")
+	// ErrExists is returned when link with given path already exists
+	ErrExists = errors.New("link already exists")
+
+	errDBInUse = errors.New("database is in use by a running urlshort; stop it or use the admin API")
+)
+
+// openBoltDB opens database for subcommands, the lock of database
+// held by running server is reported as errDBInUse
+func openBoltDB(path string, readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, errDBInUse
+	}
+	return db, err
+}
+
+// createLinksBucket creates links bucket if it doesn't exist
+func createLinksBucket(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(linksBucket))
+		return err
+	})
+}
+
+// decodeLink decodes link stored as JSON, values of databases
+// created before links had options are plain URLs
+func decodeLink(path, v []byte) (Link, error) {
+	if len(v) == 0 || v[0] != '{' {
+		return Link{Path: string(path), URL: string(v)}, nil
+	}
+
+	var l Link
+	if err := json.Unmarshal(v, &l); err != nil {
+		return Link{}, err
+	}
+	l.Path = string(path)
+	return l, nil
+}
+
+// putLink stores link as JSON in the bucket
+func putLink(b *bolt.Bucket, l Link) error {
+	v, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if isPattern(l.Path) && b.Get([]byte(l.Path)) == nil {
+		if err := changePatterns(b.Tx()); err != nil {
+			return err
+		}
+	}
+	return b.Put([]byte(l.Path), v)
+}
+
+// removeLink deletes link from the bucket
+func removeLink(b *bolt.Bucket, path []byte) error {
+	if isPattern(string(path)) {
+		if err := changePatterns(b.Tx()); err != nil {
+			return err
+		}
+	}
+	return b.Delete(path)
+}
+
+// changePatterns changes version of pattern links
+func changePatterns(tx *bolt.Tx) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(patternsBucket))
+	if err != nil {
+		return err
+	}
+	_, err = b.NextSequence()
+	return err
+}
+
+// getLink returns link of the path from database
+func getLink(db *bolt.DB, path string) (Link, error) {
+	var l Link
+	err := db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(linksBucket)).Get([]byte(path))
+		if v == nil {
+			return ErrNotFound
+		}
+		var err error
+		l, err = decodeLink([]byte(path), v)
+		return err
+	})
+
+	return l, err
+}
+
+// listLinks returns all links from database sorted by path
+func listLinks(db *bolt.DB) ([]Link, error) {
+	links := []Link{}
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(linksBucket)).ForEach(func(k, v []byte) error {
+			l, err := decodeLink(k, v)
+			if err != nil {
+				return err
+			}
+			links = append(links, l)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// createLink puts new link to database
+func createLink(db *bolt.DB, l Link) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(linksBucket))
+		if b.Get([]byte(l.Path)) != nil {
+			return ErrExists
+		}
+		return putLink(b, l)
+	})
+}
+
+// updateLink changes the existing link, its counted hits are kept
+func updateLink(db *bolt.DB, l Link) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(linksBucket))
+		v := b.Get([]byte(l.Path))
+		if v == nil {
+			return ErrNotFound
+		}
+		old, err := decodeLink([]byte(l.Path), v)
+		if err != nil {
+			return err
+		}
+		l.Hits = old.Hits
+		return putLink(b, l)
+	})
+}
+
+// deleteLink removes link from database
+func deleteLink(db *bolt.DB, path string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(linksBucket))
+		if b.Get([]byte(path)) == nil {
+			return ErrNotFound
+		}
+		return removeLink(b, []byte(path))
+	})
+}
+
+// hitLink counts redirect of the link at now. Links without limit
+// of hits are only read, so redirects don't wait for the writer
+// lock. Limited links are hit again in single writable transaction,
+// so they are never redirected more times.
+func hitLink(db *bolt.DB, path string, now time.Time) (Link, error) {
+	var (
+		l       Link
+		changed bool
+	)
+	err := db.View(func(tx *bolt.Tx) (err error) {
+		l, changed, err = hitStored(tx.Bucket([]byte(linksBucket)), path, now)
+		return err
+	})
+	if err != nil || !changed {
+		return l, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) (err error) {
+		b := tx.Bucket([]byte(linksBucket))
+		l, changed, err = hitStored(b, path, now)
+		if err != nil || !changed {
+			return err
+		}
+		return putLink(b, l)
+	})
+
+	return l, err
+}
+
+// hitStored returns the link of the bucket with counted redirect at
+// now like Link.hit
+func hitStored(b *bolt.Bucket, path string, now time.Time) (Link, bool, error) {
+	v := b.Get([]byte(path))
+	if v == nil {
+		return Link{}, false, ErrNotFound
+	}
+	l, err := decodeLink([]byte(path), v)
+	if err != nil {
+		return Link{}, false, err
+	}
+	return l.hit(now)
+}
+
+// BoltStore is LinkStore kept in BoltDB
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore creates BoltStore and its bucket if it doesn't exist
+func NewBoltStore(db *bolt.DB) (*BoltStore, error) {
+	if err := createLinksBucket(db); err != nil {
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Lookup returns link of the path
+func (s *BoltStore) Lookup(path string) (Link, error) {
+	return getLink(s.db, path)
+}
+
+// List returns all links sorted by path
+func (s *BoltStore) List() ([]Link, error) {
+	return listLinks(s.db)
+}
+
+// Put creates or changes the link
+func (s *BoltStore) Put(l Link) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putLink(tx.Bucket([]byte(linksBucket)), l)
+	})
+}
+
+// Delete removes the link
+func (s *BoltStore) Delete(path string) error {
+	return deleteLink(s.db, path)
+}
+
+// Hit counts redirect of the link at now
+func (s *BoltStore) Hit(path string, now time.Time) (Link, error) {
+	return hitLink(s.db, path, now)
+}
+
+// PatternVersion returns version of pattern links
+func (s *BoltStore) PatternVersion() (uint64, error) {
+	var v uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(patternsBucket)); b != nil {
+			v = b.Sequence()
+		}
+		return nil
+	})
+	return v, err
+}
@@ -2,6 +2,8 @@ package urlshort
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -37,17 +39,35 @@ const (
 )
 
 type appEnv struct {
-	yamlPath  string
-	jsonPath  string
-	yamlLinks []byte
-	jsonLinks []byte
+	dbPath     string
+	sqlitePath string
+	yamlPath   string
+	jsonPath   string
+	// reloadInterval is how often link files are checked for
+	// changes, zero disables checks
+	reloadInterval time.Duration
+	// sweepInterval is how often expired links are removed from
+	// BoltDB, zero disables sweeping
+	sweepInterval time.Duration
+	// policy limits domains links can redirect to
+	policy DomainPolicy
+	// adminToken is required by admin API, the API isn't served
+	// if it is empty
+	adminToken string
 }
 
 func CLI(args []string) int {
-	app := appEnv{
-		yamlLinks: []byte(yamlLinks),
-		jsonLinks: []byte(jsonLinks),
+	if len(args) > 0 && args[0] == "shorten" {
+		return shortenCLI(args[1:])
 	}
+	if len(args) > 0 && args[0] == "stats" {
+		return statsCLI(args[1:])
+	}
+	if len(args) > 0 && args[0] == "import" {
+		return importCLI(args[1:])
+	}
+
+	var app appEnv
 
 	err := app.fromArgs(args)
 	if err != nil {
@@ -64,32 +84,42 @@ func CLI(args []string) int {
 
 func (app *appEnv) fromArgs(args []string) error {
 	fl := flag.NewFlagSet("urlshort", flag.ContinueOnError)
+	fl.StringVar(&app.dbPath, "db", defaultDB, "a BoltDB file with links, created if it doesn't exist")
+	fl.StringVar(&app.sqlitePath, "sqlite", "", "a SQLite database file with links table, used after BoltDB")
 	fl.StringVar(&app.yamlPath, "yaml", "", "a yaml file in the format of \"- path: path  url: url\"")
 	fl.StringVar(&app.jsonPath, "json", "", "a json file in the format of \"[{\"path\": \"path\", \"link\": \"link\"}]\"")
 
+	fl.DurationVar(&app.reloadInterval, "reload-interval", defaultReloadInterval, "how often yaml and json files are checked for changes, 0 to reload only on SIGHUP")
+	fl.DurationVar(&app.sweepInterval, "sweep-interval", defaultSweepInterval, "how often expired links are removed from BoltDB, 0 to keep them")
+	fl.Var((*domainList)(&app.policy.Allow), "allow-domains", "comma separated domains links may redirect to, all by default, demo links are disabled by any domain policy")
+	fl.Var((*domainList)(&app.policy.Deny), "deny-domains", "comma separated domains links must not redirect to")
+	fl.StringVar(&app.adminToken, "admin-token", "", "a token required by admin API in \"Authorization: Bearer <token>\" header, the API is disabled if it is empty")
+
 	if err := fl.Parse(args); err != nil {
 		return err
 	}
 
-	// loading data from file if it was specified
-	if app.yamlPath != "" {
-		res, err := readFile(app.yamlPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "got bad output type: %v\n", app.yamlPath)
-			fl.Usage()
-			return flag.ErrHelp
-		}
-		app.yamlLinks = res
+	if app.reloadInterval < 0 {
+		fmt.Fprintf(os.Stderr, "got bad reload interval: %v\n", app.reloadInterval)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+	if app.sweepInterval < 0 {
+		fmt.Fprintf(os.Stderr, "got bad sweep interval: %v\n", app.sweepInterval)
+		fl.Usage()
+		return flag.ErrHelp
 	}
 
-	if app.jsonPath != "" {
-		res, err := readFile(app.jsonPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "got bad output type: %v\n", app.jsonPath)
+	// checking files exist if they were specified
+	for _, name := range []string{app.sqlitePath, app.yamlPath, app.jsonPath} {
+		if name == "" {
+			continue
+		}
+		if _, err := os.Stat(name); err != nil {
+			fmt.Fprintf(os.Stderr, "got bad file: %v\n", name)
 			fl.Usage()
 			return flag.ErrHelp
 		}
-		app.jsonLinks = res
 	}
 
 	return nil
@@ -104,63 +134,203 @@ func readFile(fileName string) ([]byte, error) {
 	return result, nil
 }
 
+// fileStore returns store of the links file if it was specified,
+// otherwise store of demo links, which is empty when domain policy
+// is set
+func (app *appEnv) fileStore(path, format, demo string) (LinkStore, error) {
+	if path != "" {
+		return openFileStore(path, format, app.policy)
+	}
+	if !app.policy.empty() {
+		return NewMapStore(nil), nil
+	}
+
+	links, lines, err := parseLinks([]byte(demo), format)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateLinks("", links, lines, app.policy); err != nil {
+		return nil, err
+	}
+	return NewMapStore(buildMap(links)), nil
+}
+
+// validateStores checks links of database stores, sources of
+// problems are named after database files
+func (app *appEnv) validateStores(stores []LinkStore) error {
+	var problems LinkErrors
+	for _, s := range stores {
+		source := "BoltDB " + app.dbPath
+		if _, ok := s.(*SQLiteStore); ok {
+			source = "SQLite " + app.sqlitePath
+		}
+
+		err := validateStore(source, s, app.policy)
+		var errs LinkErrors
+		switch {
+		case errors.As(err, &errs):
+			problems = append(problems, errs...)
+		case err != nil:
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// watch reloads link files every reload interval if they are
+// changed and on SIGHUP until ctx is done
+func (app *appEnv) watch(ctx context.Context, stores ...LinkStore) {
+	var files []*FileStore
+	for _, s := range stores {
+		if f, ok := s.(*FileStore); ok {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	if app.reloadInterval > 0 {
+		for _, f := range files {
+			go f.Watch(ctx, app.reloadInterval)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Println("Got SIGHUP, reloading link files")
+				for _, f := range files {
+					f.reloadAndLog()
+				}
+			}
+		}
+	}()
+}
+
 func (app *appEnv) run() error {
 	mux := defaultMux()
 
-	// Create BoltDB database
-	db, err := bolt.Open("my.db", 0600, &bolt.Options{Timeout: 1 * time.Second})
+	// Open BoltDB database, it is kept between runs
+	db, err := bolt.Open(app.dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
 		return err
 	}
 
 	// Properly close database
 	defer func() {
-		dbPath := db.Path()
 		if err := db.Close(); err != nil {
 			log.Println(err)
 		}
+	}()
 
-		if err := os.Remove(dbPath); err != nil {
-			log.Println(err)
+	// Fill in demo data on the first run, it isn't checked against
+	// the domain policy, so it is used only without one
+	if app.policy.empty() {
+		if err := fillBoltDB(db); err != nil {
+			return err
 		}
-	}()
+	} else {
+		log.Println("Demo links are disabled by domain policy")
+	}
 
-	// Fill in data
-	err = fillBoltDB(db)
+	// Collect link stores in order of precedence, BoltDB comes
+	// first so links changed with admin API override the others
+	boltStore, err := NewBoltStore(db)
 	if err != nil {
 		return err
 	}
+	stores := []LinkStore{boltStore}
+
+	// Stop background work of stores on exit
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Build the MapHandler using the mux as the fallback
-	pathsToUrls := map[string]string{
-		"/urlshort-godoc": "https://godoc.org/github.com/gophercises/urlshort",
-		"/yaml-godoc":     "https://godoc.org/gopkg.in/yaml.v2",
+	// Remove expired links in background
+	if app.sweepInterval > 0 {
+		go boltStore.Sweeper(ctx, app.sweepInterval)
 	}
-	mapHandler := MapHandler(pathsToUrls, mux)
 
-	// Build the YAMLHandler using the mapHandler as the
-	// fallback
-	yamlHandler, err := YAMLHandler(app.yamlLinks, mapHandler)
+	if app.sqlitePath != "" {
+		sqlDB, err := sql.Open("sqlite3", app.sqlitePath)
+		if err != nil {
+			return err
+		}
+		defer sqlDB.Close()
+
+		sqliteStore, err := NewSQLiteStore(sqlDB)
+		if err != nil {
+			return err
+		}
+		stores = append(stores, sqliteStore)
+	}
+
+	// Links of databases may be stored before the domain policy
+	// was set or changed by others, problems of all of them are
+	// reported at once
+	if err := app.validateStores(stores); err != nil {
+		return err
+	}
+
+	jsonStore, err := app.fileStore(app.jsonPath, "json", jsonLinks)
 	if err != nil {
 		return err
 	}
-	// Build the JSONHandler using the YAMLHandler as the
-	// fallback
-	jsonHandler, err := JSONHandler(app.jsonLinks, yamlHandler)
+	yamlStore, err := app.fileStore(app.yamlPath, "yaml", yamlLinks)
 	if err != nil {
 		return err
 	}
-	// Build the BoltHandler using the JSONHandler as the
+	// Reload link files when they are changed
+	app.watch(ctx, jsonStore, yamlStore)
+
+	stores = append(stores, jsonStore, yamlStore)
+	if app.policy.empty() {
+		stores = append(stores, NewMapStore(map[string]string{
+			"/urlshort-godoc": "https://godoc.org/github.com/gophercises/urlshort",
+			"/yaml-godoc":     "https://godoc.org/gopkg.in/yaml.v2",
+		}))
+	}
+
+	// Build the StoreHandler of all stores using the mux as the
 	// fallback
-	boltHandler, err := BoltHandler(db, jsonHandler)
+	links := NewCompositeStore(stores...)
+	linksHandler := StoreHandler(links, mux)
+
+	// Record redirects in background
+	analytics, err := NewAnalytics(db)
 	if err != nil {
 		return err
 	}
+	defer analytics.Close()
+
+	handler := http.NewServeMux()
+	handler.Handle("/", TrackHandler(analytics, linksHandler))
+
+	// Serve admin API next to redirects only to token holders
+	if app.adminToken != "" {
+		apiHandler, err := APIHandler(db, links, app.policy)
+		if err != nil {
+			return err
+		}
+		handler.Handle(apiPrefix, TokenHandler(app.adminToken, apiHandler))
+	} else {
+		log.Println("Admin API is disabled, set -admin-token to enable it")
+	}
 
 	// Create server
 	srv := &http.Server{
 		Addr:    ":8080",
-		Handler: boltHandler,
+		Handler: handler,
 	}
 
 	// Launch server
@@ -171,7 +341,7 @@ func (app *appEnv) run() error {
 		}
 	}()
 
-	// Listen for interrupt signal to close and cleanup database, close http server
+	// Listen for interrupt signal to close database and http server
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
@@ -184,18 +354,22 @@ func (app *appEnv) run() error {
 	return nil
 }
 
-// fillBoltDB insert some data in BoltDB database
+// fillBoltDB insert some data in BoltDB database if links bucket
+// doesn't exist yet, so links changed later are left as they are
 func fillBoltDB(db *bolt.DB) error {
 	if err := db.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucket([]byte("links"))
+		if tx.Bucket([]byte(linksBucket)) != nil {
+			return nil
+		}
+		b, err := tx.CreateBucket([]byte(linksBucket))
 		if err != nil {
 			return err
 		}
 
-		if err := b.Put([]byte("/bolt"), []byte("https://pkg.go.dev/go.etcd.io/bbolt")); err != nil {
+		if err := putLink(b, Link{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"}); err != nil {
 			return err
 		}
-		if err := b.Put([]byte("/yandex"), []byte("https://yandex.ru")); err != nil {
+		if err := putLink(b, Link{Path: "/yandex", URL: "https://yandex.ru"}); err != nil {
 			return err
 		}
 
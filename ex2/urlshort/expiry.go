@@ -0,0 +1,132 @@
+package urlshort
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// defaultSweepInterval is how often expired links are removed from
+// BoltDB
+const defaultSweepInterval = time.Minute
+
+var (
+	// ErrExpired is returned when the link is followed after its
+	// expiry time
+	ErrExpired = errors.New("link expired")
+	// ErrExhausted is returned when the link is followed after its
+	// maximum number of hits
+	ErrExhausted = errors.New("link hits exhausted")
+	// ErrDisabled is returned when disabled link is followed
+	ErrDisabled = errors.New("link disabled")
+)
+
+// check returns error if the link can't be redirected at now
+func (l Link) check(now time.Time) error {
+	switch {
+	case l.Disabled:
+		return ErrDisabled
+	case l.expired(now):
+		return ErrExpired
+	case l.MaxHits > 0 && l.Hits >= l.MaxHits:
+		return ErrExhausted
+	}
+	return nil
+}
+
+// expired reports whether the link is expired at now
+func (l Link) expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// hit returns the link with counted redirect at now, ok is false
+// when hits aren't limited and nothing has to be saved
+func (l Link) hit(now time.Time) (Link, bool, error) {
+	if err := l.check(now); err != nil {
+		return l, false, err
+	}
+	if l.MaxHits == 0 {
+		return l, false, nil
+	}
+	l.Hits++
+	return l, true, nil
+}
+
+// equal reports whether links are the same
+func (l Link) equal(o Link) bool {
+	if (l.ExpiresAt == nil) != (o.ExpiresAt == nil) {
+		return false
+	}
+	if l.ExpiresAt != nil && !l.ExpiresAt.Equal(*o.ExpiresAt) {
+		return false
+	}
+	l.ExpiresAt, o.ExpiresAt = nil, nil
+	return l == o
+}
+
+// sweepLinks removes links expired at now from database
+func sweepLinks(db *bolt.DB, now time.Time) ([]Link, error) {
+	var swept []Link
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(linksBucket))
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			l, err := decodeLink(k, v)
+			if err != nil {
+				return err
+			}
+			if l.expired(now) {
+				expired = append(expired, k)
+				swept = append(swept, l)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// keys are deleted after iteration as bucket can't be
+		// changed during ForEach
+		for _, k := range expired {
+			if err := removeLink(b, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return swept, nil
+}
+
+// Sweep removes expired links from the store
+func (s *BoltStore) Sweep(now time.Time) ([]Link, error) {
+	return sweepLinks(s.db, now)
+}
+
+// Sweeper removes expired links every interval until ctx is done
+func (s *BoltStore) Sweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			swept, err := s.Sweep(now)
+			if err != nil {
+				log.Printf("Sweeping expired links failed: %v", err)
+				continue
+			}
+			for _, l := range swept {
+				log.Printf("Removed expired link %s", l.Path)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package urlshort
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestLinkCheck(t *testing.T) {
+	now := time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		link Link
+		want error
+	}{
+		{"plain", Link{}, nil},
+		{"not expired", Link{ExpiresAt: &future}, nil},
+		{"expired", Link{ExpiresAt: &past}, ErrExpired},
+		{"expires now", Link{ExpiresAt: &now}, ErrExpired},
+		{"hits left", Link{MaxHits: 2, Hits: 1}, nil},
+		{"exhausted", Link{MaxHits: 2, Hits: 2}, ErrExhausted},
+		{"disabled", Link{Disabled: true, ExpiresAt: &past}, ErrDisabled},
+	}
+	for _, test := range tests {
+		if got := test.link.check(now); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestStoreHandlerGone(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	s, err := NewBoltStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, l := range []Link{
+		{Path: "/once", URL: "https://golang.org", MaxHits: 1},
+		{Path: "/old", URL: "https://golang.org", ExpiresAt: &past},
+		{Path: "/off", URL: "https://golang.org", Disabled: true},
+	} {
+		if err := s.Put(l); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	h := StoreHandler(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/once", http.StatusFound},
+		{"/once", http.StatusGone},
+		{"/old", http.StatusGone},
+		{"/off", http.StatusNotFound},
+		{"/missing", http.StatusTeapot},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("request #%d of %s: got status %d, want %d", i+1, test.path, w.Code, test.status)
+		}
+	}
+
+	if l, _ := s.Lookup("/once"); l.Hits != 1 {
+		t.Errorf("got %d hits, want 1", l.Hits)
+	}
+}
+
+func TestBoltStoreHit(t *testing.T) {
+	db := openTestDB(t)
+	s, err := NewBoltStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, l := range []Link{
+		{Path: "/go", URL: "https://golang.org"},
+		{Path: "/once", URL: "https://golang.org", MaxHits: 1},
+	} {
+		if err := s.Put(l); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// links without limit of hits aren't written
+	writes := db.Stats().TxStats.Write
+	if _, err := s.Hit("/go", time.Now()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := db.Stats().TxStats.Write - writes; n != 0 {
+		t.Errorf("got %d writes of unlimited link, want none", n)
+	}
+
+	if l, err := s.Hit("/once", time.Now()); err != nil || l.Hits != 1 {
+		t.Errorf("got %+v and error %v, want counted hit", l, err)
+	}
+	if _, err := s.Hit("/once", time.Now()); err != ErrExhausted {
+		t.Errorf("got error %v, want ErrExhausted", err)
+	}
+	if _, err := s.Hit("/missing", time.Now()); err != ErrNotFound {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLiteStoreHit(t *testing.T) {
+	db, err := sql.Open("sqlite3", tempFile(t, "links.sqlite", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	// table created before links had options is migrated
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO links (path, url) VALUES ('/go', 'https://golang.org')`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := NewSQLiteStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Hit("/go", time.Now()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expires := time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+	if err := s.Put(Link{Path: "/go", URL: "https://golang.org", MaxHits: 1, ExpiresAt: &expires}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l, err := s.Hit("/go", expires.Add(-time.Second))
+	if err != nil || l.Hits != 1 || !l.ExpiresAt.Equal(expires) {
+		t.Errorf("got %+v and error %v, want counted hit", l, err)
+	}
+	if _, err := s.Hit("/go", expires.Add(-time.Second)); err != ErrExhausted {
+		t.Errorf("got error %v, want ErrExhausted", err)
+	}
+}
+
+func TestSweepLinks(t *testing.T) {
+	db := openTestDB(t)
+	s, err := NewBoltStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	for _, l := range []Link{
+		{Path: "/old", URL: "https://golang.org", ExpiresAt: &past},
+		{Path: "/new", URL: "https://golang.org", ExpiresAt: &future},
+		{Path: "/go", URL: "https://golang.org"},
+	} {
+		if err := s.Put(l); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	swept, err := s.Sweep(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(swept) != 1 || swept[0].Path != "/old" {
+		t.Errorf("got swept %v, want /old", swept)
+	}
+	links, err := s.List()
+	if err != nil || len(links) != 2 {
+		t.Errorf("got links %v and error %v, want 2 links left", links, err)
+	}
+}
+
+func TestDecodeLegacyLink(t *testing.T) {
+	db := openTestDB(t)
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(linksBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("/go"), []byte("https://golang.org"))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := getLink(db, "/go")
+	if err != nil || l != (Link{Path: "/go", URL: "https://golang.org"}) {
+		t.Errorf("got %+v and error %v, want plain URL link", l, err)
+	}
+}
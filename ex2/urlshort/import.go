@@ -0,0 +1,197 @@
+package urlshort
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ImportResult is the number of imported links by outcome
+type ImportResult struct {
+	Added   int
+	Updated int
+	Skipped int
+}
+
+// importLinks puts links to database in single transaction, links
+// with existing paths are skipped unless overwrite is set
+func importLinks(db *bolt.DB, links []Link, overwrite bool) (ImportResult, error) {
+	var res ImportResult
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(linksBucket))
+		if err != nil {
+			return err
+		}
+
+		for _, l := range links {
+			v := b.Get([]byte(l.Path))
+			if v == nil {
+				res.Added++
+				if err := putLink(b, l); err != nil {
+					return err
+				}
+				continue
+			}
+
+			old, err := decodeLink([]byte(l.Path), v)
+			if err != nil {
+				return err
+			}
+			if !overwrite || old.equal(l) {
+				res.Skipped++
+				continue
+			}
+			res.Updated++
+			if err := putLink(b, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return res, nil
+}
+
+// parseLinks parses links in yaml or json format and returns lines
+// they are defined at
+func parseLinks(data []byte, format string) ([]Link, []int, error) {
+	switch format {
+	case "yaml":
+		return parseYAML(data)
+	case "json":
+		return parseJSON(data)
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q, use yaml or json", format)
+	}
+}
+
+// fileFormat returns format of links file by its extension
+func fileFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	default:
+		return ""
+	}
+}
+
+// importEnv represents parsed arguments of import subcommand
+type importEnv struct {
+	dbPath    string
+	format    string
+	overwrite bool
+	policy    DomainPolicy
+	files     []string
+	out       io.Writer
+}
+
+// importCLI runs import subcommand and returns its exit status
+func importCLI(args []string) int {
+	app := importEnv{out: os.Stdout}
+
+	err := app.fromArgs(args)
+	if err != nil {
+		return 2
+	}
+
+	if err = app.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func (app *importEnv) fromArgs(args []string) error {
+	fl := flag.NewFlagSet("urlshort import", flag.ContinueOnError)
+	fl.StringVar(&app.dbPath, "db", defaultDB, "a BoltDB file to import links to")
+	fl.StringVar(&app.format, "format", "", "the format of files, yaml or json, detected by extension by default")
+	fl.BoolVar(&app.overwrite, "overwrite", false, "replace existing links instead of skipping them")
+	fl.Var((*domainList)(&app.policy.Allow), "allow-domains", "comma separated domains links may redirect to, all by default")
+	fl.Var((*domainList)(&app.policy.Deny), "deny-domains", "comma separated domains links must not redirect to")
+	fl.Usage = func() {
+		fmt.Fprintln(fl.Output(), "Usage: urlshort import [flags] file...")
+		fl.PrintDefaults()
+	}
+
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+
+	if fl.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "expected at least one file")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+	app.files = fl.Args()
+
+	if app.format != "" && app.format != "yaml" && app.format != "json" {
+		fmt.Fprintf(os.Stderr, "got bad format: %v\n", app.format)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+	for _, name := range app.files {
+		if app.format == "" && fileFormat(name) == "" {
+			fmt.Fprintf(os.Stderr, "got file of unknown format: %v, use -format\n", name)
+			fl.Usage()
+			return flag.ErrHelp
+		}
+	}
+
+	return nil
+}
+
+func (app *importEnv) run() error {
+	// read and validate every file before changing database, all
+	// problems of all files are reported
+	links := make([][]Link, len(app.files))
+	var problems LinkErrors
+	for i, name := range app.files {
+		data, err := readFile(name)
+		if err != nil {
+			return err
+		}
+
+		format := app.format
+		if format == "" {
+			format = fileFormat(name)
+		}
+		var lines []int
+		links[i], lines, err = parseLinks(data, format)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := validateLinks(name, links[i], lines, app.policy); err != nil {
+			problems = append(problems, err.(LinkErrors)...)
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	db, err := openBoltDB(app.dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for i, name := range app.files {
+		res, err := importLinks(db, links[i], app.overwrite)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(app.out, "%s: %d added, %d updated, %d skipped\n", name, res.Added, res.Updated, res.Skipped)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package urlshort
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportLinks(t *testing.T) {
+	db := openTestDB(t)
+	links := []Link{
+		{Path: "/go", URL: "https://golang.org"},
+		{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"},
+	}
+	res, err := importLinks(db, links, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != (ImportResult{Added: 2}) {
+		t.Errorf("got %+v, want 2 added", res)
+	}
+
+	links = []Link{
+		{Path: "/go", URL: "https://go.dev"},
+		{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"},
+		{Path: "/yaml", URL: "https://pkg.go.dev/gopkg.in/yaml.v3"},
+	}
+	res, err = importLinks(db, links, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != (ImportResult{Added: 1, Skipped: 2}) {
+		t.Errorf("got %+v, want 1 added and 2 skipped", res)
+	}
+	if got, _ := getLink(db, "/go"); got.URL != "https://golang.org" {
+		t.Errorf("got url %q, want existing link kept", got.URL)
+	}
+
+	res, err = importLinks(db, links, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res != (ImportResult{Updated: 1, Skipped: 2}) {
+		t.Errorf("got %+v, want 1 updated and 2 skipped", res)
+	}
+	if got, _ := getLink(db, "/go"); got.URL != "https://go.dev" {
+		t.Errorf("got url %q, want overwritten link", got.URL)
+	}
+}
+
+func TestFillBoltDB(t *testing.T) {
+	db := openTestDB(t)
+	if err := fillBoltDB(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := deleteLink(db, "/bolt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// second run keeps existing links as they are
+	if err := fillBoltDB(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := getLink(db, "/bolt"); err != ErrNotFound {
+		t.Errorf("got error %v, want deleted link to stay deleted", err)
+	}
+}
+
+func TestImportEnvRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "urlshort")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"links.yml":  "- path: /go\n  url: https://golang.org\n",
+		"links.json": `[{"path": "/go", "url": "https://go.dev"}, {"path": "/bolt", "url": "https://pkg.go.dev/go.etcd.io/bbolt"}]`,
+		"bad.yaml":   "- path: /api\n  url: https://golang.org\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	app := importEnv{
+		dbPath: filepath.Join(dir, "test.db"),
+		files:  []string{filepath.Join(dir, "links.yml"), filepath.Join(dir, "links.json")},
+		out:    &out,
+	}
+	if err := app.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"1 added, 0 updated, 0 skipped", "1 added, 0 updated, 1 skipped"}
+	for _, w := range want {
+		if !strings.Contains(out.String(), w) {
+			t.Errorf("got output %q, want it to contain %q", out.String(), w)
+		}
+	}
+
+	app.files = []string{filepath.Join(dir, "bad.yaml")}
+	if err := app.run(); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("got error %v, want reserved path error", err)
+	}
+}
+
+func TestImportDBInUse(t *testing.T) {
+	db := openTestDB(t)
+
+	app := importEnv{
+		dbPath: db.Path(),
+		files:  []string{tempFile(t, "links.yaml", "- path: /go\n  url: https://golang.org\n")},
+		out:    ioutil.Discard,
+	}
+	if err := app.run(); err != errDBInUse {
+		t.Errorf("got error %v, want %v", err, errDBInUse)
+	}
+}
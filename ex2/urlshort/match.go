@@ -0,0 +1,220 @@
+package urlshort
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Pattern links have paths with parameters matching single path
+// segment, e.g. /gh/{user}/{repo}, or ending with * matching the
+// rest of the path, e.g. /docs/*. Parameters are replaced in URL
+// of the link, the rest of the path is appended to it:
+//
+//	/gh/{user}/{repo} -> https://github.com/{user}/{repo}
+//	/docs/*           -> https://pkg.go.dev
+//
+// Exact links take precedence over patterns. Patterns are compared
+// segment by segment from the left, literal segment is preferred
+// to parameter and parameter to the rest of the path.
+
+// paramRe matches parameter of pattern path or its URL
+var paramRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// PatternVersioner is implemented by stores which report changes of
+// their pattern links, matcher of patterns is built again when the
+// version changes. Patterns of other stores are read once.
+type PatternVersioner interface {
+	// PatternVersion changes whenever pattern link is added or
+	// removed
+	PatternVersion() (uint64, error)
+}
+
+// isPattern reports whether path of the link is pattern
+func isPattern(path string) bool {
+	return strings.ContainsAny(path, "{}") || strings.Contains(path, "/*")
+}
+
+// validatePattern checks parameters of pattern path and URL
+func validatePattern(l Link) error {
+	params := make(map[string]bool)
+	segs := strings.Split(l.Path[1:], "/")
+	for i, seg := range segs {
+		switch {
+		case seg == "*":
+			if i != len(segs)-1 {
+				return validationError{fmt.Errorf("path %q may have * only as the last segment", l.Path)}
+			}
+		case strings.ContainsAny(seg, "{}*"):
+			m := paramRe.FindStringSubmatch(seg)
+			if m == nil || m[0] != seg {
+				return validationError{fmt.Errorf("segment %q of path %q must be {name} parameter", seg, l.Path)}
+			}
+			if params[m[1]] {
+				return validationError{fmt.Errorf("parameter %q of path %q is repeated", m[1], l.Path)}
+			}
+			params[m[1]] = true
+		}
+	}
+
+	for _, m := range paramRe.FindAllStringSubmatch(l.URL, -1) {
+		if !params[m[1]] {
+			return validationError{fmt.Errorf("url %q has unknown parameter %q", l.URL, m[1])}
+		}
+	}
+	return nil
+}
+
+// matchNode is a node of path segments tree of patterns
+type matchNode struct {
+	literals map[string]*matchNode
+	param    *matchNode
+	// pattern is the path of the pattern ending at the node
+	pattern string
+	// rest is the path of the pattern ending with * at the node
+	rest string
+}
+
+// add puts pattern to the tree, the first one of patterns differing
+// only by names of parameters is kept
+func (n *matchNode) add(pattern string) {
+	segs := strings.Split(pattern[1:], "/")
+	for i, seg := range segs {
+		if seg == "*" && i == len(segs)-1 {
+			if n.rest == "" {
+				n.rest = pattern
+			}
+			return
+		}
+
+		if paramRe.MatchString(seg) {
+			if n.param == nil {
+				n.param = &matchNode{}
+			}
+			n = n.param
+			continue
+		}
+		if n.literals == nil {
+			n.literals = make(map[string]*matchNode)
+		}
+		if n.literals[seg] == nil {
+			n.literals[seg] = &matchNode{}
+		}
+		n = n.literals[seg]
+	}
+	if n.pattern == "" {
+		n.pattern = pattern
+	}
+}
+
+// match returns pattern matching path segments
+func (n *matchNode) match(segs []string) (string, bool) {
+	if len(segs) == 0 {
+		if n.pattern != "" {
+			return n.pattern, true
+		}
+		return n.rest, n.rest != ""
+	}
+
+	if c, ok := n.literals[segs[0]]; ok {
+		if p, ok := c.match(segs[1:]); ok {
+			return p, true
+		}
+	}
+	if n.param != nil && segs[0] != "" {
+		if p, ok := n.param.match(segs[1:]); ok {
+			return p, true
+		}
+	}
+	return n.rest, n.rest != ""
+}
+
+// expand returns URL of pattern link matched by path with values of
+// parameters and the rest of the path
+func expand(l Link, path string) string {
+	if !isPattern(l.Path) {
+		return l.URL
+	}
+
+	params := make(map[string]string)
+	patternSegs := strings.Split(l.Path[1:], "/")
+	segs := strings.Split(path[1:], "/")
+	var rest []string
+	for i, seg := range patternSegs {
+		if seg == "*" {
+			rest = segs[i:]
+			break
+		}
+		if m := paramRe.FindStringSubmatch(seg); m != nil {
+			params[m[1]] = segs[i]
+		}
+	}
+
+	u := paramRe.ReplaceAllStringFunc(l.URL, func(p string) string {
+		return url.PathEscape(params[p[1:len(p)-1]])
+	})
+	if len(rest) > 0 && !(len(rest) == 1 && rest[0] == "") {
+		for i := range rest {
+			rest[i] = url.PathEscape(rest[i])
+		}
+		u = strings.TrimSuffix(u, "/") + "/" + strings.Join(rest, "/")
+	}
+	return u
+}
+
+// withQuery returns target URL with query of the request added
+func withQuery(target, rawQuery string) string {
+	if rawQuery == "" {
+		return target
+	}
+	if strings.Contains(target, "?") {
+		return target + "&" + rawQuery
+	}
+	return target + "?" + rawQuery
+}
+
+// patternMatcher finds pattern links of the store matching paths
+type patternMatcher struct {
+	s LinkStore
+
+	mu      sync.Mutex
+	root    *matchNode
+	version uint64
+}
+
+// match returns path of the pattern link matching path
+func (m *patternMatcher) match(path string) (string, bool, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", false, nil
+	}
+
+	var version uint64
+	if v, ok := m.s.(PatternVersioner); ok {
+		var err error
+		if version, err = v.PatternVersion(); err != nil {
+			return "", false, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.root == nil || version != m.version {
+		links, err := m.s.List()
+		if err != nil {
+			return "", false, err
+		}
+		m.root = &matchNode{}
+		for _, l := range links {
+			if isPattern(l.Path) {
+				m.root.add(l.Path)
+			}
+		}
+		m.version = version
+	}
+
+	p, ok := m.root.match(strings.Split(path[1:], "/"))
+	return p, ok, nil
+}
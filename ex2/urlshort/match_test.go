@@ -0,0 +1,200 @@
+package urlshort
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchNode(t *testing.T) {
+	root := &matchNode{}
+	for _, p := range []string{
+		"/gh/{user}/{repo}",
+		"/gh/{org}/{name}",
+		"/gh/golang/{repo}",
+		"/gh/{user}/tools",
+		"/gh/*",
+		"/docs/*",
+		"/{code}",
+	} {
+		root.add(p)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/gh/semka95/gophercises", "/gh/{user}/{repo}"},
+		{"/gh/golang/go", "/gh/golang/{repo}"},
+		// literal segment is preferred from the left
+		{"/gh/golang/tools", "/gh/golang/{repo}"},
+		{"/gh/semka95/tools", "/gh/{user}/tools"},
+		{"/gh/semka95", "/gh/*"},
+		{"/gh/a/b/c", "/gh/*"},
+		{"/gh", "/gh/*"},
+		{"/docs", "/docs/*"},
+		{"/docs/a/b", "/docs/*"},
+		{"/go", "/{code}"},
+		{"/go/", ""},
+		{"/a/b", ""},
+	}
+	for _, test := range tests {
+		got, ok := root.match(strings.Split(test.path[1:], "/"))
+		if got != test.want || ok != (test.want != "") {
+			t.Errorf("%s: got %q, %v, want %q", test.path, got, ok, test.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		link Link
+		path string
+		want string
+	}{
+		{Link{Path: "/go", URL: "https://golang.org"}, "/go", "https://golang.org"},
+		{Link{Path: "/gh/{user}/{repo}", URL: "https://github.com/{user}/{repo}"}, "/gh/semka95/gophercises", "https://github.com/semka95/gophercises"},
+		{Link{Path: "/q/{term}", URL: "https://duckduckgo.com/?q={term}"}, "/q/a b", "https://duckduckgo.com/?q=a%20b"},
+		{Link{Path: "/docs/*", URL: "https://pkg.go.dev/"}, "/docs/net/http", "https://pkg.go.dev/net/http"},
+		{Link{Path: "/docs/*", URL: "https://pkg.go.dev"}, "/docs", "https://pkg.go.dev"},
+		{Link{Path: "/u/{user}/*", URL: "https://github.com/{user}"}, "/u/semka95/gophercises/tree/master", "https://github.com/semka95/gophercises/tree/master"},
+	}
+	for _, test := range tests {
+		if got := expand(test.link, test.path); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	if got := withQuery("https://example.com/?a=1", "b=2"); got != "https://example.com/?a=1&b=2" {
+		t.Errorf("got %q, want joined query", got)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		link Link
+		ok   bool
+	}{
+		{Link{Path: "/gh/{user}/{repo}", URL: "https://github.com/{user}/{repo}"}, true},
+		{Link{Path: "/docs/*", URL: "https://pkg.go.dev"}, true},
+		{Link{Path: "/*", URL: "https://golang.org"}, true},
+		{Link{Path: "/gh/{user}", URL: "https://github.com/{repo}"}, false},
+		{Link{Path: "/gh/{user}/{user}", URL: "https://github.com/{user}"}, false},
+		{Link{Path: "/gh/u{user}", URL: "https://github.com/{user}"}, false},
+		{Link{Path: "/gh/{1user}", URL: "https://github.com"}, false},
+		{Link{Path: "/docs/*/x", URL: "https://pkg.go.dev"}, false},
+	}
+	for _, test := range tests {
+		err := validateLink(test.link)
+		if (err == nil) != test.ok {
+			t.Errorf("%s -> %s: got error %v", test.link.Path, test.link.URL, err)
+		}
+	}
+}
+
+func TestStoreHandlerPatterns(t *testing.T) {
+	s, err := NewBoltStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, l := range []Link{
+		{Path: "/gh/{user}/{repo}", URL: "https://github.com/{user}/{repo}"},
+		{Path: "/gh/golang", URL: "https://github.com/golang"},
+	} {
+		if err := s.Put(l); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	h := StoreHandler(NewCompositeStore(s, NewMapStore(nil)), http.NotFoundHandler())
+
+	redirect := func(path string) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Header().Get("Location")
+	}
+
+	if got := redirect("/gh/semka95/gophercises?tab=readme"); got != "https://github.com/semka95/gophercises?tab=readme" {
+		t.Errorf("got location %q, want expanded pattern with query", got)
+	}
+	if got := redirect("/gh/golang"); got != "https://github.com/golang" {
+		t.Errorf("got location %q, want exact link", got)
+	}
+	if got := redirect("/docs/net/http"); got != "" {
+		t.Errorf("got location %q, want no redirect", got)
+	}
+
+	// added pattern is matched without restart
+	if err := s.Put(Link{Path: "/docs/*", URL: "https://pkg.go.dev"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := redirect("/docs/net/http"); got != "https://pkg.go.dev/net/http" {
+		t.Errorf("got location %q, want forwarded prefix", got)
+	}
+}
+
+func BenchmarkMatchNode(b *testing.B) {
+	root := &matchNode{}
+	for i := 0; i < 5000; i++ {
+		root.add(fmt.Sprintf("/p%d/{a}/x%d/*", i%100, i))
+	}
+	segs := strings.Split("p42/value/x4942/rest/of/path", "/")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := root.match(segs); !ok {
+			b.Fatal("expected match")
+		}
+	}
+}
+
+func TestSQLitePatternVersion(t *testing.T) {
+	db, err := sql.Open("sqlite3", tempFile(t, "links.sqlite", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	s, err := NewSQLiteStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		change  func() error
+		changed bool
+	}{
+		{"put link", func() error { return s.Put(Link{Path: "/go", URL: "https://golang.org"}) }, false},
+		{"put pattern", func() error { return s.Put(Link{Path: "/gh/{user}", URL: "https://github.com/{user}"}) }, true},
+		{"update pattern", func() error { return s.Put(Link{Path: "/gh/{user}", URL: "https://gitlab.com/{user}"}) }, false},
+		{"hit pattern", func() error { _, err := s.Hit("/gh/{user}", time.Now()); return err }, false},
+		{"rename pattern", func() error {
+			_, err := db.Exec(`UPDATE links SET path = '/gl/{user}' WHERE path = '/gh/{user}'`)
+			return err
+		}, true},
+		{"reopen store", func() error { _, err := NewSQLiteStore(db); return err }, false},
+		{"delete link", func() error { return s.Delete("/go") }, false},
+		{"delete pattern", func() error { return s.Delete("/gl/{user}") }, true},
+	}
+
+	version, err := s.PatternVersion()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, test := range tests {
+		if err := test.change(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		v, err := s.PatternVersion()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if (v != version) != test.changed {
+			t.Errorf("%s: got version %d after %d, want changed: %v", test.name, v, version, test.changed)
+		}
+		version = v
+	}
+}
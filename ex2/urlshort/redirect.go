@@ -0,0 +1,59 @@
+package urlshort
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// redirectStatuses are status codes links can redirect with
+var redirectStatuses = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// referrerPolicies are values of Referrer-Policy header
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+// status returns the redirect status code of the link
+func (l Link) status() int {
+	if l.Status == 0 {
+		return http.StatusFound
+	}
+	return l.Status
+}
+
+// setHeaders sets headers of the redirect to the link
+func (l Link) setHeaders(h http.Header) {
+	if l.CacheControl != "" {
+		h.Set("Cache-Control", l.CacheControl)
+	}
+	if l.ReferrerPolicy != "" {
+		h.Set("Referrer-Policy", l.ReferrerPolicy)
+	}
+}
+
+// validateRedirect checks status code and headers of the link
+func validateRedirect(l Link) error {
+	if l.Status != 0 && !redirectStatuses[l.Status] {
+		return validationError{fmt.Errorf("status %d of %q must be 301, 302, 307 or 308", l.Status, l.Path)}
+	}
+	if l.ReferrerPolicy != "" && !referrerPolicies[l.ReferrerPolicy] {
+		return validationError{fmt.Errorf("unknown referrer policy %q of %q", l.ReferrerPolicy, l.Path)}
+	}
+	if strings.ContainsAny(l.CacheControl, "\r\n") {
+		return validationError{fmt.Errorf("cache control of %q must be single line", l.Path)}
+	}
+	return nil
+}
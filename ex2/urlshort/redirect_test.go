@@ -0,0 +1,98 @@
+package urlshort
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStoreHandlerRedirect(t *testing.T) {
+	yml := `
+- path: /moved
+  url: https://go.dev
+  status: 301
+  cache_control: max-age=86400
+- path: /temp
+  url: https://golang.org
+  status: 307
+  referrer_policy: no-referrer
+- path: /plain
+  url: https://golang.org
+`
+	s, err := NewFileStore(tempFile(t, "links.yaml", yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := StoreHandler(s, http.NotFoundHandler())
+
+	tests := []struct {
+		path     string
+		status   int
+		cache    string
+		referrer string
+	}{
+		{"/moved", http.StatusMovedPermanently, "max-age=86400", ""},
+		{"/temp", http.StatusTemporaryRedirect, "", "no-referrer"},
+		{"/plain", http.StatusFound, "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, test.status)
+		}
+		if got := w.Header().Get("Cache-Control"); got != test.cache {
+			t.Errorf("%s: got Cache-Control %q, want %q", test.path, got, test.cache)
+		}
+		if got := w.Header().Get("Referrer-Policy"); got != test.referrer {
+			t.Errorf("%s: got Referrer-Policy %q, want %q", test.path, got, test.referrer)
+		}
+	}
+}
+
+func TestRedirectOptionsStored(t *testing.T) {
+	l := Link{Path: "/go", URL: "https://go.dev", Status: 308, CacheControl: "no-store", ReferrerPolicy: "origin"}
+
+	boltStore, err := NewBoltStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db, err := sql.Open("sqlite3", tempFile(t, "links.sqlite", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	sqliteStore, err := NewSQLiteStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, s := range map[string]LinkStore{"bolt": boltStore, "sqlite": sqliteStore} {
+		if err := s.Put(l); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if got, err := s.Lookup("/go"); err != nil || got != l {
+			t.Errorf("%s: got %+v and error %v, want %+v", name, got, err, l)
+		}
+	}
+}
+
+func TestValidateRedirect(t *testing.T) {
+	tests := []struct {
+		link Link
+		ok   bool
+	}{
+		{Link{Path: "/go", URL: "https://go.dev", Status: 308}, true},
+		{Link{Path: "/go", URL: "https://go.dev", Status: 200}, false},
+		{Link{Path: "/go", URL: "https://go.dev", Status: 303}, false},
+		{Link{Path: "/go", URL: "https://go.dev", ReferrerPolicy: "strict-origin"}, true},
+		{Link{Path: "/go", URL: "https://go.dev", ReferrerPolicy: "everyone"}, false},
+		{Link{Path: "/go", URL: "https://go.dev", CacheControl: "no-cache\r\nSet-Cookie: a=b"}, false},
+	}
+	for _, test := range tests {
+		if err := validateLink(test.link); (err == nil) != test.ok {
+			t.Errorf("%+v: got error %v", test.link, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package urlshort
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// defaultReloadInterval is how often link files are checked for
+// changes
+const defaultReloadInterval = 2 * time.Second
+
+// LinkChange is the link of the path changed from Old to New
+type LinkChange struct {
+	Path string
+	Old  Link
+	New  Link
+}
+
+// LinkDiff is the difference between two sets of links
+type LinkDiff struct {
+	Added   []Link
+	Changed []LinkChange
+	Removed []Link
+}
+
+// Empty reports whether links are the same
+func (d LinkDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// diffLinks returns changes from old to new links sorted by path,
+// counted hits aren't compared
+func diffLinks(old, new map[string]Link) LinkDiff {
+	var d LinkDiff
+	for _, l := range sortedLinks(new) {
+		o, ok := old[l.Path]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, l)
+		case !withHits(o, l.Hits).equal(l):
+			d.Changed = append(d.Changed, LinkChange{Path: l.Path, Old: o, New: l})
+		}
+	}
+	for _, l := range sortedLinks(old) {
+		if _, ok := new[l.Path]; !ok {
+			d.Removed = append(d.Removed, l)
+		}
+	}
+	return d
+}
+
+// loadLinks reads and validates links of the file, URLs must be
+// allowed by the policy
+func loadLinks(path, format string, policy DomainPolicy) (map[string]Link, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	links, lines, err := parseLinks(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := validateLinks(path, links, lines, policy); err != nil {
+		return nil, err
+	}
+	return linkMap(links), nil
+}
+
+// Reload reads the file again and replaces links of the store at
+// once, links are kept as they are if the file is invalid
+func (s *FileStore) Reload() (LinkDiff, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return LinkDiff{}, err
+	}
+	links, err := loadLinks(s.path, s.format, s.policy)
+	if err != nil {
+		return LinkDiff{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := diffLinks(s.links, links)
+	// hits counted since the file was read are kept
+	for path, l := range links {
+		if old, ok := s.links[path]; ok && old.Hits > l.Hits {
+			links[path] = withHits(l, old.Hits)
+		}
+	}
+	s.links = links
+	s.modTime, s.size = info.ModTime(), info.Size()
+	if len(d.Added) > 0 || len(d.Removed) > 0 {
+		s.patterns++
+	}
+	return d, nil
+}
+
+// modified reports whether the file was changed since it was read
+// or written by the store
+func (s *FileStore) modified() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+}
+
+// Watch checks the file every interval and reloads it when it is
+// changed until ctx is done
+func (s *FileStore) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.modified() {
+				s.reloadAndLog()
+			}
+		}
+	}
+}
+
+// reloadAndLog reloads the file and logs changed links
+func (s *FileStore) reloadAndLog() {
+	d, err := s.Reload()
+	if err != nil {
+		log.Printf("Reloading %s failed, keeping old links: %v", s.path, err)
+		return
+	}
+	if d.Empty() {
+		log.Printf("Reloaded %s, no changes", s.path)
+		return
+	}
+
+	log.Printf("Reloaded %s: %d added, %d changed, %d removed", s.path, len(d.Added), len(d.Changed), len(d.Removed))
+	for _, l := range d.Added {
+		log.Printf("  + %s %s", l.Path, l.URL)
+	}
+	for _, c := range d.Changed {
+		log.Printf("  ~ %s %s -> %s", c.Path, c.Old.URL, c.New.URL)
+	}
+	for _, l := range d.Removed {
+		log.Printf("  - %s %s", l.Path, l.URL)
+	}
+}
+
+// withHits returns the link with given number of hits
+func withHits(l Link, hits int) Link {
+	l.Hits = hits
+	return l
+}
@@ -0,0 +1,99 @@
+package urlshort
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffLinks(t *testing.T) {
+	old := linkMap([]Link{
+		{Path: "/go", URL: "https://golang.org"},
+		{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"},
+		{Path: "/yaml", URL: "https://pkg.go.dev/gopkg.in/yaml.v3", Hits: 3},
+	})
+	new := linkMap([]Link{
+		{Path: "/go", URL: "https://go.dev"},
+		{Path: "/yaml", URL: "https://pkg.go.dev/gopkg.in/yaml.v3"},
+		{Path: "/sqlite", URL: "https://sqlite.org"},
+	})
+
+	want := LinkDiff{
+		Added: []Link{{Path: "/sqlite", URL: "https://sqlite.org"}},
+		Changed: []LinkChange{{
+			Path: "/go",
+			Old:  Link{Path: "/go", URL: "https://golang.org"},
+			New:  Link{Path: "/go", URL: "https://go.dev"},
+		}},
+		Removed: []Link{{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"}},
+	}
+	if got := diffLinks(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if !diffLinks(old, old).Empty() {
+		t.Error("expected empty diff of same links")
+	}
+}
+
+func TestFileStoreReload(t *testing.T) {
+	path := tempFile(t, "links.yaml", "- path: /go\n  url: https://golang.org\n")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("- path: /go\n  url: https://go.dev\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := s.Reload()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Changed) != 1 || len(d.Added) != 0 || len(d.Removed) != 0 {
+		t.Errorf("got diff %+v, want single changed link", d)
+	}
+	if got, _ := s.Lookup("/go"); got.URL != "https://go.dev" {
+		t.Errorf("got url %q, want reloaded https://go.dev", got.URL)
+	}
+
+	// invalid files are not applied
+	for _, data := range []string{"- path: /go\n  url: [", "- path: /api\n  url: https://go.dev\n"} {
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.Reload(); err == nil {
+			t.Errorf("%q: expected error", data)
+		}
+		if got, _ := s.Lookup("/go"); got.URL != "https://go.dev" {
+			t.Errorf("%q: got url %q, want old link kept", data, got.URL)
+		}
+	}
+}
+
+func TestFileStoreWatch(t *testing.T) {
+	path := tempFile(t, "links.json", `[{"path": "/go", "url": "https://golang.org"}]`)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx, 10*time.Millisecond)
+
+	data := `[{"path": "/go", "url": "https://golang.org"}, {"path": "/bolt", "url": "https://pkg.go.dev/go.etcd.io/bbolt"}]`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := s.Lookup("/bolt"); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("changed file wasn't reloaded")
+}
@@ -0,0 +1,287 @@
+package urlshort
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	base62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// defaultCodeLength is the length of random short codes
+	defaultCodeLength = 6
+	// maxCodeTries limits attempts to generate unused short code
+	maxCodeTries = 10
+	shortenPath  = "/api/shorten"
+	defaultDB    = "my.db"
+	defaultBase  = "http://localhost:8080"
+)
+
+// reserved are the first path segments which can't be used by
+// links as they are used by the service itself
+var reserved = map[string]bool{
+	"api":         true,
+	"admin":       true,
+	"static":      true,
+	"health":      true,
+	"favicon.ico": true,
+	"robots.txt":  true,
+}
+
+// aliasRe matches custom short codes
+var aliasRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// errNoCode is returned when random short code can't be generated
+var errNoCode = errors.New("can't generate unused short code")
+
+// Shortener creates links with generated short codes
+type Shortener struct {
+	db *bolt.DB
+	// Random generates random codes instead of sequential ones
+	Random bool
+	// Length is the length of random codes
+	Length int
+	// Policy limits domains links can be created to
+	Policy DomainPolicy
+	// Links are served next to created ones, aliases must not
+	// conflict with them
+	Links LinkStore
+}
+
+// NewShortener creates Shortener storing links in BoltDB,
+// short codes are base62 encoded numbers of the bucket sequence
+func NewShortener(db *bolt.DB) (*Shortener, error) {
+	if err := createLinksBucket(db); err != nil {
+		return nil, err
+	}
+
+	return &Shortener{db: db, Length: defaultCodeLength, Links: &BoltStore{db: db}}, nil
+}
+
+// Shorten creates link to url with the alias as a short code or
+// with generated one if alias is empty
+func (s *Shortener) Shorten(url, alias string) (Link, error) {
+	l := Link{URL: url}
+
+	if alias != "" {
+		if !aliasRe.MatchString(alias) {
+			return Link{}, validationError{fmt.Errorf("alias %q must contain only letters, digits, _ and -", alias)}
+		}
+		l.Path = "/" + alias
+		if err := checkLink(l, s.Policy); err != nil {
+			return Link{}, err
+		}
+		if err := checkConflict(s.Links, l.Path); err != nil {
+			return Link{}, err
+		}
+		return l, createLink(s.db, l)
+	}
+
+	if err := checkLink(Link{Path: "/code", URL: url}, s.Policy); err != nil {
+		return Link{}, err
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(linksBucket))
+		for i := 0; ; i++ {
+			code, err := s.code(b)
+			if err != nil {
+				return err
+			}
+
+			l.Path = "/" + code
+			if !reserved[code] && b.Get([]byte(l.Path)) == nil {
+				return putLink(b, l)
+			}
+
+			// sequential codes are skipped until unused one is
+			// found, random ones are tried limited number of times
+			if s.Random && i == maxCodeTries {
+				return errNoCode
+			}
+		}
+	})
+	if err != nil {
+		return Link{}, err
+	}
+
+	return l, nil
+}
+
+// code returns the next short code
+func (s *Shortener) code(b *bolt.Bucket) (string, error) {
+	if s.Random {
+		return randomCode(s.Length)
+	}
+
+	seq, err := b.NextSequence()
+	if err != nil {
+		return "", err
+	}
+	return encodeBase62(seq), nil
+}
+
+// encodeBase62 returns base62 representation of n
+func encodeBase62(n uint64) string {
+	if n == 0 {
+		return base62[:1]
+	}
+
+	var b []byte
+	for ; n > 0; n /= 62 {
+		b = append(b, base62[n%62])
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// randomCode returns random base62 string of given length
+func randomCode(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(base62)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = base62[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// shortenRequest is the body of shorten API request
+type shortenRequest struct {
+	URL   string `json:"url"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// shortenResponse is the body of shorten API response
+type shortenResponse struct {
+	Link
+	ShortURL string `json:"short_url"`
+}
+
+// shorten handles POST /api/shorten with {"url": "url"} or
+// {"url": "url", "alias": "code"} and returns created link
+func (api linksAPI) shorten(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeError(w, errMethod)
+		return
+	}
+
+	var req shortenRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	l, err := api.shortener.Shorten(req.URL, req.Alias)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	w.Header().Set("Location", linksPath+l.Path)
+	writeJSON(w, http.StatusCreated, shortenResponse{Link: l, ShortURL: scheme + "://" + r.Host + l.Path})
+}
+
+// shortenEnv represents parsed arguments of shorten subcommand
+type shortenEnv struct {
+	dbPath string
+	alias  string
+	random bool
+	length int
+	base   string
+	policy DomainPolicy
+	url    string
+}
+
+// shortenCLI runs shorten subcommand and returns its exit status
+func shortenCLI(args []string) int {
+	var app shortenEnv
+
+	err := app.fromArgs(args)
+	if err != nil {
+		return 2
+	}
+
+	if err = app.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func (app *shortenEnv) fromArgs(args []string) error {
+	fl := flag.NewFlagSet("urlshort shorten", flag.ContinueOnError)
+	fl.StringVar(&app.dbPath, "db", defaultDB, "a BoltDB file to store the link to")
+	fl.StringVar(&app.alias, "alias", "", "a custom short code instead of generated one")
+	fl.BoolVar(&app.random, "random", false, "generate random short code instead of sequential one")
+	fl.IntVar(&app.length, "length", defaultCodeLength, "the length of random short code")
+	fl.StringVar(&app.base, "base", defaultBase, "the base URL of the service to print short URL")
+	fl.Var((*domainList)(&app.policy.Allow), "allow-domains", "comma separated domains links may redirect to, all by default")
+	fl.Var((*domainList)(&app.policy.Deny), "deny-domains", "comma separated domains links must not redirect to")
+	fl.Usage = func() {
+		fmt.Fprintln(fl.Output(), "Usage: urlshort shorten [flags] url")
+		fl.PrintDefaults()
+	}
+
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+
+	if fl.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "expected single url")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+	app.url = fl.Arg(0)
+
+	if app.length < 1 {
+		fmt.Fprintf(os.Stderr, "got bad length: %v\n", app.length)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	return nil
+}
+
+func (app *shortenEnv) run() error {
+	db, err := openBoltDB(app.dbPath, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	s, err := NewShortener(db)
+	if err != nil {
+		return err
+	}
+	s.Random = app.random
+	s.Length = app.length
+	s.Policy = app.policy
+
+	l, err := s.Shorten(app.url, app.alias)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.TrimSuffix(app.base, "/") + l.Path)
+	return nil
+}
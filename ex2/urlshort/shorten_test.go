@@ -0,0 +1,119 @@
+package urlshort
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBase62(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0"},
+		{9, "9"},
+		{10, "a"},
+		{61, "Z"},
+		{62, "10"},
+		{3843, "ZZ"},
+	}
+	for _, test := range tests {
+		if got := encodeBase62(test.n); got != test.want {
+			t.Errorf("encodeBase62(%d) = %q, want %q", test.n, got, test.want)
+		}
+	}
+}
+
+func TestShortenerShorten(t *testing.T) {
+	db := openTestDB(t)
+	s, err := NewShortener(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// sequential code skips paths which are already used
+	if err := createLink(db, Link{Path: "/2", URL: "https://example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	for i := 0; i < 2; i++ {
+		l, err := s.Shorten("https://golang.org", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		paths = append(paths, l.Path)
+	}
+	if paths[0] != "/1" || paths[1] != "/3" {
+		t.Errorf("got paths %v, want /1 and /3", paths)
+	}
+
+	s.Random = true
+	l, err := s.Shorten("https://golang.org", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(l.Path) != defaultCodeLength+1 {
+		t.Errorf("got path %q, want random code of length %d", l.Path, defaultCodeLength)
+	}
+	if got, err := getLink(db, l.Path); err != nil || got.URL != "https://golang.org" {
+		t.Errorf("got url %q and error %v, want stored link", got.URL, err)
+	}
+
+	errTests := []struct {
+		name  string
+		url   string
+		alias string
+		want  error
+	}{
+		{"taken alias", "https://golang.org", "1", ErrExists},
+		{"reserved alias", "https://golang.org", "api", nil},
+		{"bad alias", "https://golang.org", "a/b", nil},
+		{"bad url", "ftp://golang.org", "", nil},
+	}
+	for _, test := range errTests {
+		_, err := s.Shorten(test.url, test.alias)
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+			continue
+		}
+		var ve validationError
+		if test.want != nil && !errors.Is(err, test.want) || test.want == nil && !errors.As(err, &ve) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestAPIShorten(t *testing.T) {
+	api, err := APIHandler(openTestDB(t), nil, DomainPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://sho.rt/api/shorten", strings.NewReader(`{"url": "https://golang.org", "alias": "go"}`))
+	api.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var got shortenResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Path != "/go" || got.ShortURL != "http://sho.rt/go" {
+		t.Errorf("got %+v, want /go link", got)
+	}
+}
+
+func TestShortenDBInUse(t *testing.T) {
+	db := openTestDB(t)
+
+	app := shortenEnv{dbPath: db.Path(), url: "https://golang.org"}
+	if err := app.run(); !errors.Is(err, errDBInUse) {
+		t.Errorf("got error %v, want %v", err, errDBInUse)
+	}
+}
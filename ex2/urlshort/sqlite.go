@@ -0,0 +1,242 @@
+package urlshort
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	// registers sqlite3 driver of database/sql
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS links (
+	path TEXT PRIMARY KEY,
+	url  TEXT NOT NULL
+)`
+
+// sqliteVersionSchema keeps version of pattern links in single row
+// of link_patterns table, triggers bump it whenever pattern is added
+// or removed by anyone
+const sqliteVersionSchema = `CREATE TABLE IF NOT EXISTS link_patterns (
+	id      INTEGER PRIMARY KEY CHECK (id = 1),
+	version INTEGER NOT NULL
+);
+INSERT OR IGNORE INTO link_patterns (id, version) VALUES (1, 0);
+CREATE TRIGGER IF NOT EXISTS link_patterns_insert AFTER INSERT ON links
+WHEN NEW.path LIKE '%{%' OR NEW.path LIKE '%}%' OR NEW.path LIKE '%/*%'
+BEGIN
+	UPDATE link_patterns SET version = version + 1;
+END;
+CREATE TRIGGER IF NOT EXISTS link_patterns_update AFTER UPDATE OF path ON links
+WHEN OLD.path LIKE '%{%' OR OLD.path LIKE '%}%' OR OLD.path LIKE '%/*%'
+	OR NEW.path LIKE '%{%' OR NEW.path LIKE '%}%' OR NEW.path LIKE '%/*%'
+BEGIN
+	UPDATE link_patterns SET version = version + 1;
+END;
+CREATE TRIGGER IF NOT EXISTS link_patterns_delete AFTER DELETE ON links
+WHEN OLD.path LIKE '%{%' OR OLD.path LIKE '%}%' OR OLD.path LIKE '%/*%'
+BEGIN
+	UPDATE link_patterns SET version = version + 1;
+END`
+
+// sqliteColumns are columns added to links table after it was
+// created, they are added to existing tables on start
+var sqliteColumns = []struct {
+	name string
+	def  string
+}{
+	{"expires_at", "TEXT"},
+	{"max_hits", "INTEGER NOT NULL DEFAULT 0"},
+	{"hits", "INTEGER NOT NULL DEFAULT 0"},
+	{"disabled", "INTEGER NOT NULL DEFAULT 0"},
+	{"status", "INTEGER NOT NULL DEFAULT 0"},
+	{"cache_control", "TEXT NOT NULL DEFAULT ''"},
+	{"referrer_policy", "TEXT NOT NULL DEFAULT ''"},
+}
+
+const sqliteSelect = `SELECT path, url, expires_at, max_hits, hits, disabled,
+	status, cache_control, referrer_policy FROM links`
+
+// SQLiteStore is LinkStore kept in links table of SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+	// hitMu serializes hits, so concurrent transactions don't fail
+	// upgrading their locks
+	hitMu sync.Mutex
+}
+
+// NewSQLiteStore creates SQLiteStore and its table if it doesn't
+// exist, db is expected to be opened with sqlite3 driver
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, err
+	}
+	if err := migrateSQLite(db); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteVersionSchema); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// migrateSQLite adds missing columns to links table
+func migrateSQLite(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('links')`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range sqliteColumns {
+		if existing[c.name] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE links ADD COLUMN ` + c.name + ` ` + c.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanner is sql.Row or sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLink reads link selected with sqliteSelect
+func scanLink(row scanner) (Link, error) {
+	var (
+		l         Link
+		expiresAt sql.NullString
+	)
+	err := row.Scan(&l.Path, &l.URL, &expiresAt, &l.MaxHits, &l.Hits, &l.Disabled,
+		&l.Status, &l.CacheControl, &l.ReferrerPolicy)
+	if err != nil {
+		return Link{}, err
+	}
+	if expiresAt.Valid {
+		t, err := time.Parse(time.RFC3339Nano, expiresAt.String)
+		if err != nil {
+			return Link{}, err
+		}
+		l.ExpiresAt = &t
+	}
+	return l, nil
+}
+
+// expiresAtValue converts expiry time of the link to column value
+func expiresAtValue(l Link) interface{} {
+	if l.ExpiresAt == nil {
+		return nil
+	}
+	return l.ExpiresAt.UTC().Format(time.RFC3339Nano)
+}
+
+// Lookup returns link of the path
+func (s *SQLiteStore) Lookup(path string) (Link, error) {
+	l, err := scanLink(s.db.QueryRow(sqliteSelect+` WHERE path = ?`, path))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrNotFound
+	}
+	return l, err
+}
+
+// List returns all links sorted by path
+func (s *SQLiteStore) List() ([]Link, error) {
+	rows, err := s.db.Query(sqliteSelect + ` ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		l, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// Put creates or changes the link
+func (s *SQLiteStore) Put(l Link) error {
+	_, err := s.db.Exec(`INSERT INTO links (path, url, expires_at, max_hits, hits, disabled,
+			status, cache_control, referrer_policy)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET url = excluded.url, expires_at = excluded.expires_at,
+			max_hits = excluded.max_hits, hits = excluded.hits, disabled = excluded.disabled,
+			status = excluded.status, cache_control = excluded.cache_control,
+			referrer_policy = excluded.referrer_policy`,
+		l.Path, l.URL, expiresAtValue(l), l.MaxHits, l.Hits, l.Disabled,
+		l.Status, l.CacheControl, l.ReferrerPolicy)
+	return err
+}
+
+// Delete removes the link
+func (s *SQLiteStore) Delete(path string) error {
+	res, err := s.db.Exec(`DELETE FROM links WHERE path = ?`, path)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PatternVersion returns version of pattern links, it changes when
+// pattern is added or removed by anyone
+func (s *SQLiteStore) PatternVersion() (uint64, error) {
+	var v uint64
+	err := s.db.QueryRow(`SELECT version FROM link_patterns WHERE id = 1`).Scan(&v)
+	return v, err
+}
+
+// Hit counts redirect of the link at now in single transaction
+func (s *SQLiteStore) Hit(path string, now time.Time) (Link, error) {
+	s.hitMu.Lock()
+	defer s.hitMu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Link{}, err
+	}
+	defer tx.Rollback()
+
+	old, err := scanLink(tx.QueryRow(sqliteSelect+` WHERE path = ?`, path))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrNotFound
+	}
+	if err != nil {
+		return Link{}, err
+	}
+
+	l, changed, err := old.hit(now)
+	if err != nil || !changed {
+		return l, err
+	}
+	if _, err := tx.Exec(`UPDATE links SET hits = ? WHERE path = ?`, l.Hits, path); err != nil {
+		return Link{}, err
+	}
+	return l, tx.Commit()
+}
@@ -0,0 +1,206 @@
+package urlshort
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	statsPath       = "/api/stats"
+	defaultInterval = "day"
+)
+
+// parseStatsRange parses interval name and since value which is
+// either RFC 3339 time or duration before now, e.g. "168h"
+func parseStatsRange(interval, since string, now time.Time) (time.Duration, time.Time, error) {
+	if interval == "" {
+		interval = defaultInterval
+	}
+	d, ok := intervals[interval]
+	if !ok {
+		return 0, time.Time{}, validationError{fmt.Errorf("unknown interval %q, use hour, day or week", interval)}
+	}
+
+	if since == "" {
+		return d, time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return d, t, nil
+	}
+	ago, err := time.ParseDuration(since)
+	if err != nil || ago < 0 {
+		return 0, time.Time{}, validationError{fmt.Errorf("bad since %q, use RFC 3339 time or duration", since)}
+	}
+
+	return d, now.Add(-ago), nil
+}
+
+// stats handles GET /api/stats with click counts of all links and
+// GET /api/stats/path?interval=day&since=168h with stats of /path
+func (api linksAPI) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, errMethod)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, statsPath)
+	if path == "" || path == "/" {
+		counts, err := clickCounts(api.db)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, counts)
+		return
+	}
+
+	q := r.URL.Query()
+	interval, since, err := parseStatsRange(q.Get("interval"), q.Get("since"), time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	stats, err := linkStats(api.db, path, interval, since)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// statsEnv represents parsed arguments of stats subcommand
+type statsEnv struct {
+	dbPath   string
+	interval string
+	since    string
+	path     string
+	out      io.Writer
+}
+
+// statsCLI runs stats subcommand and returns its exit status
+func statsCLI(args []string) int {
+	app := statsEnv{out: os.Stdout}
+
+	err := app.fromArgs(args)
+	if err != nil {
+		return 2
+	}
+
+	if err = app.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func (app *statsEnv) fromArgs(args []string) error {
+	fl := flag.NewFlagSet("urlshort stats", flag.ContinueOnError)
+	fl.StringVar(&app.dbPath, "db", defaultDB, "a BoltDB file with recorded clicks")
+	fl.StringVar(&app.interval, "interval", defaultInterval, "group clicks of the link by hour, day or week")
+	fl.StringVar(&app.since, "since", "", "count only clicks since RFC 3339 time or duration before now, e.g. 168h")
+	fl.Usage = func() {
+		fmt.Fprintln(fl.Output(), "Usage: urlshort stats [flags] [path]")
+		fl.PrintDefaults()
+	}
+
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+
+	if fl.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "expected single path")
+		fl.Usage()
+		return flag.ErrHelp
+	}
+	app.path = fl.Arg(0)
+	if app.path != "" && !strings.HasPrefix(app.path, "/") {
+		app.path = "/" + app.path
+	}
+
+	if _, _, err := parseStatsRange(app.interval, app.since, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "got bad stats range: %v\n", err)
+		fl.Usage()
+		return flag.ErrHelp
+	}
+
+	return nil
+}
+
+func (app *statsEnv) run() error {
+	if _, err := os.Stat(app.dbPath); err != nil {
+		return err
+	}
+
+	db, err := openBoltDB(app.dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if app.path == "" {
+		counts, err := clickCounts(db)
+		if err != nil {
+			return err
+		}
+		app.printCounts(counts)
+		return nil
+	}
+
+	interval, since, err := parseStatsRange(app.interval, app.since, time.Now())
+	if err != nil {
+		return err
+	}
+	stats, err := linkStats(db, app.path, interval, since)
+	if err != nil {
+		return err
+	}
+	app.printStats(stats)
+
+	return nil
+}
+
+// printCounts writes table of click counts
+func (app *statsEnv) printCounts(counts []LinkClicks) {
+	if len(counts) == 0 {
+		fmt.Fprintln(app.out, "No clicks yet.")
+		return
+	}
+
+	w := tabwriter.NewWriter(app.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tCLICKS")
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", c.Path, c.Clicks)
+	}
+	w.Flush()
+}
+
+// printStats writes clicks of the link by interval and its top
+// referrers
+func (app *statsEnv) printStats(stats LinkStats) {
+	fmt.Fprintf(app.out, "%s: %d clicks\n\n", stats.Path, stats.Clicks)
+
+	w := tabwriter.NewWriter(app.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCLICKS")
+	for _, b := range stats.Buckets {
+		fmt.Fprintf(w, "%s\t%d\n", b.Time.Format(time.RFC3339), b.Clicks)
+	}
+	w.Flush()
+
+	if len(stats.TopReferrers) == 0 {
+		return
+	}
+
+	fmt.Fprintln(app.out, "\nTop referrers:")
+	for i, ref := range stats.TopReferrers {
+		fmt.Fprintf(app.out, "%d. %s (%d)\n", i+1, ref.Referrer, ref.Clicks)
+	}
+}
@@ -0,0 +1,431 @@
+package urlshort
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LinkStore is a source of links. Lookup, Delete and Hit return
+// ErrNotFound when the path has no link, Put creates the link or
+// changes the existing one. Hit counts redirect of the link at
+// given time and returns ErrDisabled, ErrExpired or ErrExhausted
+// if the link can't be redirected.
+type LinkStore interface {
+	Lookup(path string) (Link, error)
+	List() ([]Link, error)
+	Put(l Link) error
+	Delete(path string) error
+	Hit(path string, now time.Time) (Link, error)
+}
+
+// StoreHandler will return an http.HandlerFunc (which also
+// implements http.Handler) that will attempt to map any paths
+// to their corresponding URL in the store. Every request is
+// looked up in the store, so changed links are served without
+// restart. Paths without exact link are matched with pattern
+// links, query of the request is passed to the URL. If the path
+// is not provided in the store, then the fallback http.Handler
+// will be called instead. Redirects have status code and headers
+// of the link. Expired links and links out of hits are answered
+// with 410 Gone, disabled links with 404 Not Found.
+func StoreHandler(s LinkStore, fallback http.Handler) http.HandlerFunc {
+	patterns := &patternMatcher{s: s}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		link, err := hitPath(s, patterns, r.URL.Path, time.Now())
+		switch {
+		case err == nil:
+			target := withQuery(expand(link, r.URL.Path), r.URL.RawQuery)
+			link.setHeaders(w.Header())
+			setMatched(r, link.Path)
+			http.Redirect(w, r, target, link.status())
+		case errors.Is(err, ErrNotFound):
+			fallback.ServeHTTP(w, r)
+		case errors.Is(err, ErrExpired), errors.Is(err, ErrExhausted):
+			http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+		case errors.Is(err, ErrDisabled):
+			http.NotFound(w, r)
+		default:
+			log.Println(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+// hitPath counts redirect of the exact link of the path or of the
+// pattern link matching it
+func hitPath(s LinkStore, patterns *patternMatcher, path string, now time.Time) (Link, error) {
+	// paths looking like patterns are only matched with them
+	if !isPattern(path) {
+		l, err := s.Hit(path, now)
+		if !errors.Is(err, ErrNotFound) {
+			return l, err
+		}
+	}
+
+	pattern, ok, err := patterns.match(path)
+	if err != nil {
+		return Link{}, err
+	}
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+	return s.Hit(pattern, now)
+}
+
+// MapStore is LinkStore kept in memory
+type MapStore struct {
+	mu    sync.RWMutex
+	links map[string]Link
+	// patterns is the version of pattern links
+	patterns uint64
+}
+
+// NewMapStore creates MapStore with copy of paths to urls mapping
+func NewMapStore(pathsToUrls map[string]string) *MapStore {
+	links := make(map[string]Link, len(pathsToUrls))
+	for path, url := range pathsToUrls {
+		links[path] = Link{Path: path, URL: url}
+	}
+	return &MapStore{links: links}
+}
+
+// linkMap converts []Link to map of links by path
+func linkMap(links []Link) map[string]Link {
+	m := make(map[string]Link, len(links))
+	for _, l := range links {
+		m[l.Path] = l
+	}
+	return m
+}
+
+// Lookup returns link of the path
+func (s *MapStore) Lookup(path string) (Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.links[path]
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+	return l, nil
+}
+
+// List returns all links sorted by path
+func (s *MapStore) List() ([]Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedLinks(s.links), nil
+}
+
+// Put creates or changes the link
+func (s *MapStore) Put(l Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.added(l.Path)
+	s.links[l.Path] = l
+	return nil
+}
+
+// Delete removes the link
+func (s *MapStore) Delete(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[path]; !ok {
+		return ErrNotFound
+	}
+	delete(s.links, path)
+	s.removed(path)
+	return nil
+}
+
+// PatternVersion returns version of pattern links
+func (s *MapStore) PatternVersion() (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.patterns, nil
+}
+
+// added changes version of pattern links if new pattern is going
+// to be added, s.mu is expected to be locked
+func (s *MapStore) added(path string) {
+	if _, ok := s.links[path]; !ok && isPattern(path) {
+		s.patterns++
+	}
+}
+
+// removed changes version of pattern links if the pattern was
+// removed, s.mu is expected to be locked
+func (s *MapStore) removed(path string) {
+	if isPattern(path) {
+		s.patterns++
+	}
+}
+
+// Hit counts redirect of the link at now, hits are kept in memory
+func (s *MapStore) Hit(path string, now time.Time) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.links[path]
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+	l, changed, err := old.hit(now)
+	if changed {
+		s.links[path] = l
+	}
+	return l, err
+}
+
+// sortedLinks converts map of links to []Link sorted by path
+func sortedLinks(m map[string]Link) []Link {
+	links := make([]Link, 0, len(m))
+	for _, l := range m {
+		links = append(links, l)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Path < links[j].Path
+	})
+	return links
+}
+
+// FileStore is LinkStore loaded from YAML or JSON file, changes
+// are written back to the file in the same format. Links of the
+// file changed by others are read again with Reload or Watch.
+// Hits are counted in memory and written to the file only with
+// other changes.
+type FileStore struct {
+	MapStore
+	path   string
+	format string
+	// policy limits domains of links read from the file
+	policy DomainPolicy
+	// modTime and size of the file when it was last read or
+	// written, used to notice changes made by others
+	modTime time.Time
+	size    int64
+}
+
+// NewFileStore loads links from the file, its format is detected
+// by extension
+func NewFileStore(path string) (*FileStore, error) {
+	format := fileFormat(path)
+	if format == "" {
+		return nil, fmt.Errorf("%s: unknown format, use .yaml, .yml or .json file", path)
+	}
+	return openFileStore(path, format, DomainPolicy{})
+}
+
+// openFileStore loads links from the file in yaml or json format,
+// URLs of the links must be allowed by the policy
+func openFileStore(path, format string, policy DomainPolicy) (*FileStore, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	links, err := loadLinks(path, format, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		MapStore: MapStore{links: links},
+		path:     path,
+		format:   format,
+		policy:   policy,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+	}, nil
+}
+
+// Put creates or changes the link and saves the file
+func (s *FileStore) Put(l Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.links[l.Path]
+	s.added(l.Path)
+	s.links[l.Path] = l
+	if err := s.save(); err != nil {
+		if ok {
+			s.links[l.Path] = old
+		} else {
+			delete(s.links, l.Path)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the link and saves the file
+func (s *FileStore) Delete(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.links[path]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.links, path)
+	s.removed(path)
+	if err := s.save(); err != nil {
+		s.links[path] = old
+		return err
+	}
+	return nil
+}
+
+// save replaces the file with current links, the file is never
+// left partially written
+func (s *FileStore) save() error {
+	var (
+		data []byte
+		err  error
+	)
+	links := sortedLinks(s.links)
+	if s.format == "json" {
+		data, err = json.MarshalIndent(links, "", "  ")
+	} else {
+		data, err = yaml.Marshal(links)
+	}
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// CompositeStore is LinkStore combining several stores, earlier
+// stores take precedence over later ones
+type CompositeStore struct {
+	stores []LinkStore
+}
+
+// NewCompositeStore creates CompositeStore of stores in order of
+// precedence, new links are put to the first one
+func NewCompositeStore(stores ...LinkStore) *CompositeStore {
+	return &CompositeStore{stores: stores}
+}
+
+// Lookup returns URL of the path from the first store having it
+func (s *CompositeStore) Lookup(path string) (Link, error) {
+	for _, store := range s.stores {
+		l, err := store.Lookup(path)
+		if !errors.Is(err, ErrNotFound) {
+			return l, err
+		}
+	}
+	return Link{}, ErrNotFound
+}
+
+// List returns links of all stores sorted by path, the link of
+// the path is taken from the first store having it
+func (s *CompositeStore) List() ([]Link, error) {
+	m := make(map[string]Link)
+	for i := len(s.stores) - 1; i >= 0; i-- {
+		links, err := s.stores[i].List()
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			m[l.Path] = l
+		}
+	}
+	return sortedLinks(m), nil
+}
+
+// Put creates or changes the link in the first store, so it takes
+// precedence over links of other stores
+func (s *CompositeStore) Put(l Link) error {
+	if len(s.stores) == 0 {
+		return errors.New("no stores to put link to")
+	}
+	return s.stores[0].Put(l)
+}
+
+// Delete removes the link from every store having it, otherwise the
+// link of the next store would be served instead
+func (s *CompositeStore) Delete(path string) error {
+	found := false
+	for _, store := range s.stores {
+		err := store.Delete(path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Hit counts redirect of the link in the first store having it
+func (s *CompositeStore) Hit(path string, now time.Time) (Link, error) {
+	for _, store := range s.stores {
+		l, err := store.Hit(path, now)
+		if !errors.Is(err, ErrNotFound) {
+			return l, err
+		}
+	}
+	return Link{}, ErrNotFound
+}
+
+// PatternVersion returns sum of versions of pattern links of the
+// stores, it changes whenever version of any store changes
+func (s *CompositeStore) PatternVersion() (uint64, error) {
+	var sum uint64
+	for _, store := range s.stores {
+		v, ok := store.(PatternVersioner)
+		if !ok {
+			continue
+		}
+		n, err := v.PatternVersion()
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
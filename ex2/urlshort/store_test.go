@@ -0,0 +1,194 @@
+package urlshort
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// tempFile writes data to file in temporary directory removed
+// after the test
+func tempFile(t *testing.T, name, data string) string {
+	dir, err := ioutil.TempDir("", "urlshort")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLinkStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) LinkStore{
+		"map": func(t *testing.T) LinkStore {
+			return NewMapStore(map[string]string{"/go": "https://golang.org"})
+		},
+		"yaml file": func(t *testing.T) LinkStore {
+			s, err := NewFileStore(tempFile(t, "links.yaml", "- path: /go\n  url: https://golang.org\n"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return s
+		},
+		"json file": func(t *testing.T) LinkStore {
+			s, err := NewFileStore(tempFile(t, "links.json", `[{"path": "/go", "url": "https://golang.org"}]`))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return s
+		},
+		"bolt": func(t *testing.T) LinkStore {
+			s, err := NewBoltStore(openTestDB(t))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Put(Link{Path: "/go", URL: "https://golang.org"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return s
+		},
+		"sqlite": func(t *testing.T) LinkStore {
+			db, err := sql.Open("sqlite3", tempFile(t, "links.sqlite", ""))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			s, err := NewSQLiteStore(db)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Put(Link{Path: "/go", URL: "https://golang.org"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+
+			if got, err := s.Lookup("/go"); err != nil || got.URL != "https://golang.org" {
+				t.Errorf("got url %q and error %v, want https://golang.org", got.URL, err)
+			}
+			if _, err := s.Lookup("/missing"); err != ErrNotFound {
+				t.Errorf("got error %v, want ErrNotFound", err)
+			}
+
+			if err := s.Put(Link{Path: "/go", URL: "https://go.dev"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Put(Link{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := []Link{
+				{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"},
+				{Path: "/go", URL: "https://go.dev"},
+			}
+			if got, err := s.List(); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("got links %v and error %v, want %v", got, err, want)
+			}
+
+			if err := s.Delete("/go"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Delete("/go"); err != ErrNotFound {
+				t.Errorf("got error %v, want ErrNotFound", err)
+			}
+			if _, err := s.Lookup("/go"); err != ErrNotFound {
+				t.Errorf("got error %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreSave(t *testing.T) {
+	path := tempFile(t, "links.yml", "- path: /go\n  url: https://golang.org\n")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Put(Link{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// changes are written to the file
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := reloaded.Lookup("/bolt"); err != nil || got.URL != "https://pkg.go.dev/go.etcd.io/bbolt" {
+		t.Errorf("got url %q and error %v, want saved link", got.URL, err)
+	}
+
+	if _, err := NewFileStore(tempFile(t, "links.txt", "")); err == nil {
+		t.Error("expected error of unknown format")
+	}
+}
+
+func TestCompositeStore(t *testing.T) {
+	first := NewMapStore(map[string]string{"/go": "https://go.dev"})
+	second := NewMapStore(map[string]string{
+		"/go":   "https://golang.org",
+		"/bolt": "https://pkg.go.dev/go.etcd.io/bbolt",
+	})
+	s := NewCompositeStore(first, second)
+
+	if got, _ := s.Lookup("/go"); got.URL != "https://go.dev" {
+		t.Errorf("got url %q, want url of the first store", got.URL)
+	}
+	if got, _ := s.Lookup("/bolt"); got.URL != "https://pkg.go.dev/go.etcd.io/bbolt" {
+		t.Errorf("got url %q, want url of the second store", got.URL)
+	}
+	want := []Link{
+		{Path: "/bolt", URL: "https://pkg.go.dev/go.etcd.io/bbolt"},
+		{Path: "/go", URL: "https://go.dev"},
+	}
+	if got, err := s.List(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got links %v and error %v, want %v", got, err, want)
+	}
+
+	if err := s.Put(Link{Path: "/bolt", URL: "https://github.com/etcd-io/bbolt"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := first.Lookup("/bolt"); got.URL != "https://github.com/etcd-io/bbolt" {
+		t.Errorf("got url %q, want link put to the first store", got.URL)
+	}
+
+	// deleted link is removed from every store
+	if err := s.Delete("/go"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Lookup("/go"); err != ErrNotFound {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+	if err := s.Delete("/go"); err != ErrNotFound {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreHandler(t *testing.T) {
+	s := NewMapStore(map[string]string{"/go": "https://golang.org"})
+	h := StoreHandler(s, http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/go", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://golang.org" {
+		t.Errorf("got status %d and location %q, want redirect to https://golang.org", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want fallback %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -1,9 +1,13 @@
 package urlshort
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 	"gopkg.in/yaml.v3"
@@ -13,6 +17,23 @@ import (
 type Link struct {
 	Path string `yaml:"path" json:"path"`
 	URL  string `yaml:"url" json:"url"`
+	// ExpiresAt is the time the link is gone at, nil means the
+	// link never expires
+	ExpiresAt *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+	// MaxHits is the number of redirects after which the link is
+	// gone, zero means no limit
+	MaxHits int `yaml:"max_hits,omitempty" json:"max_hits,omitempty"`
+	// Hits is the number of redirects counted for MaxHits
+	Hits int `yaml:"hits,omitempty" json:"hits,omitempty"`
+	// Disabled link is not redirected until it is enabled again
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	// Status is the redirect status code, 301, 302, 307 or 308,
+	// zero means 302 Found
+	Status int `yaml:"status,omitempty" json:"status,omitempty"`
+	// CacheControl is the Cache-Control header of the redirect
+	CacheControl string `yaml:"cache_control,omitempty" json:"cache_control,omitempty"`
+	// ReferrerPolicy is the Referrer-Policy header of the redirect
+	ReferrerPolicy string `yaml:"referrer_policy,omitempty" json:"referrer_policy,omitempty"`
 }
 
 func defaultMux() *http.ServeMux {
@@ -32,16 +53,7 @@ func hello(w http.ResponseWriter, r *http.Request) {
 // If the path is not provided in the map, then the fallback
 // http.Handler will be called instead.
 func MapHandler(pathsToUrls map[string]string, fallback http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Path
-
-		if link, ok := pathsToUrls[path]; ok {
-			http.Redirect(w, r, link, http.StatusFound)
-			return
-		}
-
-		fallback.ServeHTTP(w, r)
-	}
+	return StoreHandler(NewMapStore(pathsToUrls), fallback)
 }
 
 // YAMLHandler will parse the provided YAML and then return
@@ -55,31 +67,48 @@ func MapHandler(pathsToUrls map[string]string, fallback http.Handler) http.Handl
 //     - path: /some-path
 //       url: https://www.some-url.com/demo
 //
-// The only errors that can be returned all related to having
-// invalid YAML data.
+// Errors are returned for invalid YAML data and invalid links,
+// all invalid links are reported with their lines as LinkErrors.
 //
 // See MapHandler to create a similar http.HandlerFunc via
 // a mapping of paths to urls.
 func YAMLHandler(yml []byte, fallback http.Handler) (http.HandlerFunc, error) {
-	parsedYaml, err := parseYAML(yml)
+	links, lines, err := parseYAML(yml)
 	if err != nil {
 		return nil, err
 	}
-	pathMap := buildMap(parsedYaml)
+	if err := validateLinks("", links, lines, DomainPolicy{}); err != nil {
+		return nil, err
+	}
 
-	return MapHandler(pathMap, fallback), nil
+	return StoreHandler(&MapStore{links: linkMap(links)}, fallback), nil
 }
 
-// parseYAML parses YAML and returns array of Links
-func parseYAML(yml []byte) ([]Link, error) {
-	var link []Link
+// parseYAML parses YAML and returns array of Links and lines they
+// are defined at
+func parseYAML(yml []byte) ([]Link, []int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(yml, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, nil
+	}
 
-	err := yaml.Unmarshal(yml, &link)
-	if err != nil {
-		return nil, err
+	seq := doc.Content[0]
+	if seq.Kind != yaml.SequenceNode {
+		return nil, nil, fmt.Errorf("line %d: expected list of links", seq.Line)
+	}
+	links := make([]Link, len(seq.Content))
+	lines := make([]int, len(seq.Content))
+	for i, item := range seq.Content {
+		if err := item.Decode(&links[i]); err != nil {
+			return nil, nil, err
+		}
+		lines[i] = item.Line
 	}
 
-	return link, nil
+	return links, lines, nil
 }
 
 // JSONHandler will parse the provided JSON and then return
@@ -97,31 +126,80 @@ func parseYAML(yml []byte) ([]Link, error) {
 //  	}
 //	]
 //
-// The only errors that can be returned all related to having
-// invalid JSON data.
+// Errors are returned for invalid JSON data and invalid links,
+// all invalid links are reported with their lines as LinkErrors.
 //
 // See MapHandler to create a similar http.HandlerFunc via
 // a mapping of paths to urls.
 func JSONHandler(jsn []byte, fallback http.Handler) (http.HandlerFunc, error) {
-	parsedJson, err := parseJSON(jsn)
+	links, lines, err := parseJSON(jsn)
 	if err != nil {
 		return nil, err
 	}
-	pathMap := buildMap(parsedJson)
+	if err := validateLinks("", links, lines, DomainPolicy{}); err != nil {
+		return nil, err
+	}
 
-	return MapHandler(pathMap, fallback), nil
+	return StoreHandler(&MapStore{links: linkMap(links)}, fallback), nil
 }
 
-// parseJSON parses JSON and returns array of Links
-func parseJSON(jsn []byte) ([]Link, error) {
-	var link []Link
-
-	err := json.Unmarshal(jsn, &link)
+// parseJSON parses JSON and returns array of Links and lines they
+// are defined at
+func parseJSON(jsn []byte) ([]Link, []int, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsn))
+	tok, err := dec.Token()
+	if err == io.EOF || (err == nil && tok == nil) {
+		return nil, nil, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, jsonError(jsn, err)
+	}
+	if tok != json.Delim('[') {
+		return nil, nil, fmt.Errorf("line %d: expected list of links", lineAt(jsn, dec.InputOffset()))
 	}
 
-	return link, nil
+	var links []Link
+	var lines []int
+	for dec.More() {
+		line := lineAt(jsn, dec.InputOffset())
+		var l Link
+		if err := dec.Decode(&l); err != nil {
+			return nil, nil, jsonError(jsn, err)
+		}
+		links = append(links, l)
+		lines = append(lines, line)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, jsonError(jsn, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("line %d: unexpected data after list of links", lineAt(jsn, dec.InputOffset()))
+	}
+
+	return links, lines, nil
+}
+
+// jsonError adds line of syntax and type errors to err
+func jsonError(jsn []byte, err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Errorf("line %d: %v", lineAt(jsn, e.Offset), err)
+	case *json.UnmarshalTypeError:
+		return fmt.Errorf("line %d: %v", lineAt(jsn, e.Offset), err)
+	}
+	return err
+}
+
+// lineAt returns line of the first non-space byte at or after
+// offset of data
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
 }
 
 // buildMap converts []Link to map[string]string
@@ -134,27 +212,18 @@ func buildMap(parsedYaml []Link) map[string]string {
 	return pathMap
 }
 
-// BoltHandler will get data from BoltDB and then return
-// an http.HandlerFunc (which also implements http.Handler)
-// that will attempt to map any paths to their corresponding
-// URL. If the path is not provided in the database, then the
-// fallback http.Handler will be called instead.
+// BoltHandler will return an http.HandlerFunc (which also
+// implements http.Handler) that will attempt to map any paths
+// to their corresponding URL stored in BoltDB. Every request
+// is looked up in the database, so links changed with
+// APIHandler are served without restart. If the path is not
+// provided in the database, then the fallback http.Handler
+// will be called instead.
 func BoltHandler(db *bolt.DB, fallback http.Handler) (http.HandlerFunc, error) {
-	pathMap := make(map[string]string)
-
-	if err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("links"))
-
-		if err := b.ForEach(func(k, v []byte) error {
-			pathMap[string(k)] = string(v)
-			return nil
-		}); err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
+	s, err := NewBoltStore(db)
+	if err != nil {
 		return nil, err
 	}
 
-	return MapHandler(pathMap, fallback), nil
+	return StoreHandler(s, fallback), nil
 }
@@ -0,0 +1,195 @@
+package urlshort
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// LinkError is a problem of the link defined at Line of Source
+// file, Line is zero for links of databases
+type LinkError struct {
+	Source string
+	Line   int
+	Path   string
+	Err    error
+}
+
+func (e LinkError) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("%s: link %s: %v", e.Source, e.Path, e.Err)
+	case e.Source == "":
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	default:
+		return fmt.Sprintf("%s:%d: %v", e.Source, e.Line, e.Err)
+	}
+}
+
+func (e LinkError) Unwrap() error {
+	return e.Err
+}
+
+// LinkErrors is the list of all problems of links
+type LinkErrors []LinkError
+
+func (e LinkErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	return fmt.Sprintf("%d problems with links:\n\t%s", len(msgs), strings.Join(msgs, "\n\t"))
+}
+
+// DomainPolicy limits hosts links can redirect to. Host matches the
+// domain if it is the domain or its subdomain. Denied domains take
+// precedence, empty Allow list allows every domain that isn't
+// denied.
+type DomainPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// empty reports whether the policy allows every domain
+func (p DomainPolicy) empty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+// Check returns error if host of URL isn't allowed
+func (p DomainPolicy) Check(rawURL string) error {
+	if p.empty() {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return validationError{fmt.Errorf("bad url: %v", err)}
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchDomain(host, p.Deny) {
+		return validationError{fmt.Errorf("domain of url %q is denied", rawURL)}
+	}
+	if len(p.Allow) > 0 && !matchDomain(host, p.Allow) {
+		return validationError{fmt.Errorf("domain of url %q isn't allowed", rawURL)}
+	}
+	return nil
+}
+
+// matchDomain reports whether host is one of domains or their
+// subdomain
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainList is flag.Value of comma separated domains
+type domainList []string
+
+func (l *domainList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *domainList) Set(s string) error {
+	for _, d := range strings.Split(s, ",") {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d == "" {
+			continue
+		}
+		if strings.ContainsAny(d, "/:") {
+			return fmt.Errorf("domain %q must not have scheme, port or path", d)
+		}
+		*l = append(*l, d)
+	}
+	return nil
+}
+
+// checkLink validates the link and its domain
+func checkLink(l Link, policy DomainPolicy) error {
+	if err := validateLink(l); err != nil {
+		return err
+	}
+	return policy.Check(l.URL)
+}
+
+// validateLinks checks every link of the source and returns
+// LinkErrors with all problems found. Links are expected to be
+// defined at given lines. Paths defined twice and patterns
+// differing only by names of parameters are conflicts.
+func validateLinks(source string, links []Link, lines []int, policy DomainPolicy) error {
+	var errs LinkErrors
+	first := make(map[string]int, len(links))
+	for i, l := range links {
+		line := lineOf(lines, i)
+
+		if err := checkLink(l, policy); err != nil {
+			errs = append(errs, LinkError{Source: source, Line: line, Path: l.Path, Err: err})
+		}
+
+		key := conflictKey(l.Path)
+		if prev, ok := first[key]; ok {
+			err := fmt.Errorf("path %q conflicts with %q", l.Path, links[prev].Path)
+			if prevLine := lineOf(lines, prev); prevLine > 0 {
+				err = fmt.Errorf("%v defined at line %d", err, prevLine)
+			}
+			errs = append(errs, LinkError{Source: source, Line: line, Path: l.Path, Err: validationError{err}})
+			continue
+		}
+		first[key] = i
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkConflict returns ErrExists if path conflicts with a pattern
+// of links differing only by names of parameters, the same path is
+// allowed, so it can override the link of other store
+func checkConflict(links LinkStore, path string) error {
+	all, err := links.List()
+	if err != nil {
+		return err
+	}
+	key := conflictKey(path)
+	for _, l := range all {
+		if l.Path != path && conflictKey(l.Path) == key {
+			return fmt.Errorf("%w: path %q conflicts with %q", ErrExists, path, l.Path)
+		}
+	}
+	return nil
+}
+
+// validateStore checks all links of the store like validateLinks,
+// e.g. links put to databases before the policy was set
+func validateStore(source string, s LinkStore, policy DomainPolicy) error {
+	links, err := s.List()
+	if err != nil {
+		return err
+	}
+	return validateLinks(source, links, nil, policy)
+}
+
+// lineOf returns line of i-th link or zero if it is unknown
+func lineOf(lines []int, i int) int {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return 0
+}
+
+// conflictKey returns path with names of parameters removed, so
+// paths matching the same requests have the same key
+func conflictKey(path string) string {
+	return paramRe.ReplaceAllString(path, "{}")
+}
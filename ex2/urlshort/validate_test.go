@@ -0,0 +1,290 @@
+package urlshort
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLines(t *testing.T) {
+	yml := `
+- path: /a
+  url: https://a.example
+
+- path: /b
+  url: https://b.example
+`
+	jsn := `[
+  {"path": "/a", "url": "https://a.example"},
+
+  {
+    "path": "/b",
+    "url": "https://b.example"
+  }
+]`
+
+	tests := []struct {
+		format string
+		data   string
+		lines  []int
+	}{
+		{"yaml", yml, []int{2, 5}},
+		{"json", jsn, []int{2, 4}},
+		{"yaml", "", nil},
+		{"json", "null", nil},
+	}
+	for _, test := range tests {
+		links, lines, err := parseLinks([]byte(test.data), test.format)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%s: got lines %v, want %v", test.format, lines, test.lines)
+		}
+		if len(links) != len(test.lines) {
+			t.Errorf("%s: got %d links, want %d", test.format, len(links), len(test.lines))
+		}
+	}
+
+	for _, data := range []string{`{"path": "/a"}`, "[\n{\"path\": 1}\n]", `[] []`} {
+		if _, _, err := parseLinks([]byte(data), "json"); err == nil {
+			t.Errorf("%s: expected error", data)
+		}
+	}
+	if _, _, err := parseLinks([]byte("path: /a"), "yaml"); err == nil {
+		t.Error("expected error for yaml mapping")
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	yml := `
+- path: /ok
+  url: https://golang.org
+- path: /empty
+  url: ""
+- path: relative
+  url: https://golang.org
+- path: /js
+  url: javascript:alert(1)
+- path: /ok
+  url: https://go.dev
+- path: /gh/{user}
+  url: https://github.com/{user}
+- path: /gh/{name}
+  url: https://gitlab.com/{name}
+- path: /bad
+  url: https://evil.example.com
+`
+	links, lines, err := parseYAML([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = validateLinks("links.yaml", links, lines, DomainPolicy{Deny: []string{"example.com"}})
+	var errs LinkErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error %v, want LinkErrors", err)
+	}
+
+	var got []int
+	for _, e := range errs {
+		got = append(got, e.Line)
+	}
+	if want := []int{4, 6, 8, 10, 14, 16}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got problems at lines %v, want %v:\n%v", got, want, err)
+	}
+	if msg := errs[3].Error(); msg != `links.yaml:10: path "/ok" conflicts with "/ok" defined at line 2` {
+		t.Errorf("got message %q", msg)
+	}
+	if !strings.Contains(err.Error(), "6 problems") {
+		t.Errorf("got message %q, want number of problems", err.Error())
+	}
+
+	var verr validationError
+	if !errors.As(errs[0], &verr) {
+		t.Errorf("got error %v, want validation error", errs[0])
+	}
+}
+
+func TestDomainPolicy(t *testing.T) {
+	p := DomainPolicy{Allow: []string{"golang.org", "github.com"}, Deny: []string{"gist.github.com"}}
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://golang.org/doc", true},
+		{"https://blog.golang.org", true},
+		{"https://GitHub.com:443/semka95", true},
+		{"https://notgolang.org", false},
+		{"https://gist.github.com/x", false},
+		{"https://a.gist.github.com/x", false},
+		{"https://golang.org.evil.com", false},
+	}
+	for _, test := range tests {
+		if err := p.Check(test.url); (err == nil) != test.ok {
+			t.Errorf("%s: got error %v", test.url, err)
+		}
+	}
+
+	if err := (DomainPolicy{}).Check("https://anything.example"); err != nil {
+		t.Errorf("unexpected error of empty policy: %v", err)
+	}
+}
+
+func TestDomainList(t *testing.T) {
+	var l domainList
+	if err := l.Set(" Golang.org, .github.com,,"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.Set("go.dev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (domainList{"golang.org", "github.com", "go.dev"}); !reflect.DeepEqual(l, want) {
+		t.Errorf("got %v, want %v", l, want)
+	}
+
+	for _, s := range []string{"https://golang.org", "golang.org:443", "golang.org/doc"} {
+		if err := new(domainList).Set(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestFileStorePolicy(t *testing.T) {
+	name := tempFile(t, "links.json", `[
+  {"path": "/go", "url": "https://golang.org"},
+  {"path": "/ex", "url": "https://example.com"}
+]`)
+
+	if _, err := openFileStore(name, "json", DomainPolicy{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := openFileStore(name, "json", DomainPolicy{Allow: []string{"golang.org"}})
+	if err == nil || !strings.HasPrefix(err.Error(), name+":3: ") {
+		t.Errorf("got error %v, want denied link at line 3", err)
+	}
+}
+
+func TestAPIPolicy(t *testing.T) {
+	api, err := APIHandler(openTestDB(t), nil, DomainPolicy{Deny: []string{"example.com"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{linksPath, `{"path": "/go", "url": "https://golang.org"}`, http.StatusCreated},
+		{linksPath, `{"path": "/ex", "url": "https://www.example.com"}`, http.StatusBadRequest},
+		{shortenPath, `{"url": "https://example.com"}`, http.StatusBadRequest},
+		{shortenPath, `{"url": "https://golang.org"}`, http.StatusCreated},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body)))
+		if w.Code != test.code {
+			t.Errorf("%s %s: got status %d, want %d", test.path, test.body, w.Code, test.code)
+		}
+	}
+}
+
+func TestYAMLHandlerInvalid(t *testing.T) {
+	yml := `
+- path: /a
+  url: https://a.example
+- path: /a
+  url: https://b.example
+`
+	if _, err := YAMLHandler([]byte(yml), http.NotFoundHandler()); err == nil || !strings.HasPrefix(err.Error(), "line 4: ") {
+		t.Errorf("got error %v, want duplicate at line 4", err)
+	}
+}
+
+func TestValidateStores(t *testing.T) {
+	db := openTestDB(t)
+	if err := fillBoltDB(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := NewBoltStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := appEnv{dbPath: "my.db"}
+	if err := app.validateStores([]LinkStore{s}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// seeded link is reported once the domain is denied
+	app.policy.Deny = []string{"yandex.ru"}
+	err = app.validateStores([]LinkStore{s})
+	if err == nil || !strings.HasPrefix(err.Error(), "BoltDB my.db: link /yandex: ") {
+		t.Errorf("got error %v, want denied /yandex", err)
+	}
+}
+
+func TestAPIConflicts(t *testing.T) {
+	db := openTestDB(t)
+	s, err := NewBoltStore(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	links := NewCompositeStore(s, NewMapStore(map[string]string{"/docs/{page}": "https://golang.org/{page}"}))
+	api, err := APIHandler(db, links, DomainPolicy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, linksPath, `{"path": "/gh/{user}/{repo}", "url": "https://github.com/{user}/{repo}"}`, http.StatusCreated},
+		{http.MethodPost, linksPath, `{"path": "/gh/{a}/{b}", "url": "https://github.com/{a}/{b}"}`, http.StatusConflict},
+		{http.MethodPost, linksPath, `{"path": "/docs/{name}", "url": "https://go.dev/{name}"}`, http.StatusConflict},
+		{http.MethodPost, linksPath, `{"path": "/docs/{page}", "url": "https://go.dev/{page}"}`, http.StatusCreated},
+		{http.MethodPut, linksPath + "/gh/{x}/{y}", `{"url": "https://gitlab.com/{x}/{y}"}`, http.StatusConflict},
+		{http.MethodPut, linksPath + "/gh/{user}/{repo}", `{"url": "https://gitlab.com/{user}/{repo}"}`, http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+		if w.Code != test.code {
+			t.Errorf("%s %s %s: got status %d, want %d", test.method, test.path, test.body, w.Code, test.code)
+		}
+	}
+
+	if err := validateStore("BoltDB", s, DomainPolicy{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDemoLinksPolicy(t *testing.T) {
+	tests := []struct {
+		policy DomainPolicy
+		links  int
+	}{
+		{DomainPolicy{}, 2},
+		{DomainPolicy{Allow: []string{"example.com"}}, 0},
+	}
+	for _, test := range tests {
+		app := appEnv{policy: test.policy}
+		s, err := app.fileStore("", "yaml", yamlLinks)
+		if err != nil {
+			t.Errorf("%+v: unexpected error: %v", test.policy, err)
+			continue
+		}
+		if links, _ := s.List(); len(links) != test.links {
+			t.Errorf("%+v: got %d demo links, want %d", test.policy, len(links), test.links)
+		}
+	}
+}